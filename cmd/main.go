@@ -1,41 +1,106 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"io"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
+	"github.com/elireisman/maven-index-reader-go/pkg/checkpoint"
 	"github.com/elireisman/maven-index-reader-go/pkg/config"
 	"github.com/elireisman/maven-index-reader-go/pkg/data"
 	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
 	"github.com/elireisman/maven-index-reader-go/pkg/output"
 	"github.com/elireisman/maven-index-reader-go/pkg/readers"
+	"github.com/elireisman/maven-index-reader-go/pkg/writers"
 
 	"github.com/pkg/errors"
 )
 
 var (
-	Format  string
-	Out     string
-	After   string
-	Only    string
-	Mode    string
-	Pool    int
-	Verbose bool
+	Format    string
+	Out       string
+	After     string
+	Only      string
+	Mode      string
+	Fallback  bool
+	Pool      int
+	Timeout   time.Duration
+	Legacy    bool
+	Cache     string
+	Sums      bool
+	Keyring   string
+	MetaStore string
+	State     string
+	Verbose   bool
+	CSVOpts   config.CSVOptions
+	PQOpts    config.ParquetOptions
 )
 
 func init() {
-	flag.StringVar(&Format, "format", "log", "output format: one of 'log', 'json', 'csv'")
-	flag.StringVar(&Out, "out", "", "if set, specifies the output file path. stdout if unset")
+	flag.StringVar(&Format, "format", "log", formatUsage)
+	flag.StringVar(&Out, "out", "", "if set, specifies the output file path. stdout if unset. the database file, for --format=sqlite")
+	csvFlags(flag.CommandLine)
+	parquetFlags(flag.CommandLine)
 	flag.StringVar(&After, "after", "", "value depends on --mode; RFC 3339 time string, or int chunk ID, of the last successfully processed chunk")
 	flag.StringVar(&Only, "only", "", "value depends on --mode, incompatible with --after; the single chunk ID to process")
 	flag.StringVar(&Mode, "mode", "all", "one of 'all', 'after-time', 'after-chunk', 'only-chunk'")
+	flag.BoolVar(&Fallback, "fallback-full", false, "with --mode=after-chunk, read the full index if chunks following --after are no longer published")
 	flag.IntVar(&Pool, "pool", 4, "number of goroutines enabled to scan index chunks in parallel")
+	flag.DurationVar(&Timeout, "timeout", 0, "if set, cancels the run after the given duration, like '90m'")
+	flag.BoolVar(&Legacy, "legacy", false, "read the legacy Lucene-based .zip index; only supports --mode=all")
+	flag.StringVar(&Cache, "cache", "", "if set, caches downloaded index files under this directory, revalidating on later runs")
+	flag.BoolVar(&Sums, "verify-checksums", false, "verify each index file against its published .sha1/.md5 checksums")
+	flag.StringVar(&Keyring, "keyring", "", "if set, verify each index file's detached .asc signature against this OpenPGP keyring file")
+	flag.StringVar(&MetaStore, "meta", "", "if set, discovers the index and chain IDs on first run, persisting them to this JSON file, and expects them on later runs")
+	flag.StringVar(&State, "state", "", "if set, resumes after the checkpoint recorded in this JSON state file, overriding --mode and --after, and records a new one once output is flushed. --format=sqlite records it in the database if unset, unless --mode, --after or --only is passed")
 	flag.BoolVar(&Verbose, "verbose", false, "log config, skipped records, and progress verbosely")
 }
 
+// the --format usage, naming every registered output Sink
+var formatUsage = "output format: one of the registered sinks '" + strings.Join(output.SinkNames(), "', '") + "'"
+
+// csvFlags - register the --format=csv settings on the flag set
+func csvFlags(fs *flag.FlagSet) {
+	fs.Func("fields", "with --format=csv, a comma-separated list of the columns to write, like 'record_type,groupId,artifactId,version'", func(v string) error {
+		CSVOpts.Fields = strings.Split(v, ",")
+		return nil
+	})
+	fs.Func("csv-columns", "with --format=csv and no --fields, one of 'union' (one file, with the columns of every record type) or 'per-type' (one --out file per record type)", func(v string) error {
+		columns, ok := config.CSVColumnModes[strings.ToLower(v)]
+		if !ok {
+			return errors.Errorf("unknown CSV column mode %q", v)
+		}
+		CSVOpts.Columns = columns
+		return nil
+	})
+	fs.Func("csv-delimiter", "with --format=csv, the field delimiter: a single character, or 'tab'. defaults to ','", func(v string) error {
+		if v == "tab" || v == `\t` {
+			v = "\t"
+		}
+		if utf8.RuneCountInString(v) != 1 {
+			return errors.Errorf("delimiter %q is not a single character", v)
+		}
+		CSVOpts.Delimiter, _ = utf8.DecodeRuneInString(v)
+		return nil
+	})
+	fs.StringVar(&CSVOpts.ListSeparator, "csv-list-separator", "|", "with --format=csv, joins list values like class names and groups")
+	fs.StringVar(&CSVOpts.TimeFormat, "csv-time-format", time.RFC3339Nano, "with --format=csv, the Go time layout timestamps are written with")
+}
+
+// parquetFlags - register the --format=parquet settings on the flag set
+func parquetFlags(fs *flag.FlagSet) {
+	fs.Int64Var(&PQOpts.RowGroupSize, "parquet-row-group-size", 128*1024*1024, "with --format=parquet, the target bytes buffered per row group")
+	fs.Int64Var(&PQOpts.RowsPerFile, "parquet-rows-per-file", 0, "with --format=parquet, if set, rolls --out over to numbered part files of at most this many rows")
+}
+
 // implements readers.FilterFunc contract to filter
 // extracted Maven Central records of interest
 func filterFn(record data.Record) bool {
@@ -53,58 +118,352 @@ func filterFn(record data.Record) bool {
 	return true
 }
 
-func main() {
-	flag.Parse()
+// runContext - cancels in-flight downloads, chunk scans and output on
+// SIGINT, SIGTERM or timeout, flushing records already consumed
+func runContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if Timeout <= 0 {
+		return ctx, stop
+	}
 
-	logger := log.Default()
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+func centralConfig() config.Index {
+	meta := config.Meta{
+		// from https://repo1.maven.org/maven2/.index/nexus-maven-repository-index.properties
+		ID:      "central",
+		ChainID: "1318453614498",
+		File:    "nexus-maven-repository-index",
+	}
+	if MetaStore != "" {
+		// discovered on first contact, and tracked across chain rotations
+		meta = config.Meta{File: meta.File, Store: MetaStore}
+	}
+
+	// any other --format names a Sink registered with the output package
+	out := config.Output{File: Out, CSV: CSVOpts, Parquet: PQOpts}
+	if format, ok := config.OutputFormats[strings.ToLower(Format)]; ok {
+		out.Format = format
+	} else {
+		out.Sink = Format
+	}
 
-	mavenCentralCfg := config.Index{
+	return config.Index{
 		Verbose: Verbose,
-		Meta: config.Meta{
-			// from https://repo1.maven.org/maven2/.index/nexus-maven-repository-index.properties
-			ID:      "central",
-			ChainID: "1318453614498",
-			File:    "nexus-maven-repository-index",
-		},
+		Meta:    meta,
 		Source: config.Source{
-			Base: "https://repo1.maven.org/maven2/.index/",
-			Type: config.HTTP,
+			Base:   "https://repo1.maven.org/maven2/.index/",
+			Type:   config.HTTP,
+			Legacy: Legacy,
+			Cache:  Cache,
 		},
-		Mode: config.Mode{
-			Type:  config.ModeTypes[strings.ToLower(Mode)],
-			After: After,
-			Only:  Only,
+		Verify: config.Verify{
+			Checksums: Sums,
+			Keyring:   Keyring,
 		},
-		Output: config.Output{
-			Format: config.OutputFormats[strings.ToLower(Format)],
-			File:   Out,
+		Mode: config.Mode{
+			Type:     config.ModeTypes[strings.ToLower(Mode)],
+			After:    After,
+			Only:     Only,
+			Fallback: Fallback,
 		},
+		Output: out,
 	}
+}
+
+// mirror - the "mirror" subcommand replicates the remote index
+// into a local directory, readable with a config.Local source
+func mirror(args []string) {
+	var dest string
+	mirrorFlags := flag.NewFlagSet("mirror", flag.ExitOnError)
+	mirrorFlags.StringVar(&dest, "dest", "", "local directory to replicate the index into")
+	mirrorFlags.StringVar(&Cache, "cache", "", "if set, caches downloaded index files under this directory, revalidating on later runs")
+	mirrorFlags.DurationVar(&Timeout, "timeout", 0, "if set, cancels the run after the given duration, like '90m'")
+	mirrorFlags.BoolVar(&Sums, "verify-checksums", false, "verify each index file against its published .sha1/.md5 checksums")
+	mirrorFlags.StringVar(&Keyring, "keyring", "", "if set, verify each index file's detached .asc signature against this OpenPGP keyring file")
+	mirrorFlags.BoolVar(&Verbose, "verbose", false, "log config and progress verbosely")
+	mirrorFlags.Parse(args)
+
+	logger := log.Default()
+	if len(dest) == 0 {
+		logger.Fatalf("mirror: --dest is required")
+	}
+
+	ctx, cancel := runContext()
+	defer cancel()
+
+	mavenCentralCfg := centralConfig()
 	if err := config.Validate(logger, mavenCentralCfg); err != nil {
 		panic(err.Error())
 	}
 
-	// Fetch index properties and enumerate index chunks to be scanned
-	chunkNamesQueue := make(chan string, 16)
-	mavenCentral := readers.NewIndex(logger, chunkNamesQueue, mavenCentralCfg)
+	if _, err := writers.NewMirror(logger, mavenCentralCfg, dest).Sync(ctx); err != nil {
+		if ctx.Err() != nil {
+			logger.Fatalf("Run cancelled: %s", err)
+		}
+		panic(err.Error())
+	}
+}
 
-	err := mavenCentral.Read()
-	if err != nil {
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "mirror" {
+		mirror(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		watch(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		// the JSON Schema of each line of --format=ndjson output
+		schema, err := output.Schema()
+		if err != nil {
+			panic(err.Error())
+		}
+		os.Stdout.Write(schema)
+		return
+	}
+
+	flag.Parse()
+
+	logger := log.Default()
+
+	ctx, cancel := runContext()
+	defer cancel()
+
+	mavenCentralCfg := centralConfig()
+	if err := config.Validate(logger, mavenCentralCfg); err != nil {
+		panic(err.Error())
+	}
+
+	if err := run(ctx, logger, mavenCentralCfg, stateStore(logger, mavenCentralCfg)); err != nil {
+		if ctx.Err() != nil {
+			logger.Fatalf("Run cancelled: %s", err)
+		}
+		if readers.IsChainRotated(err) || readers.IsChainBroken(err) {
+			logger.Fatalf("Full re-ingest required, rerun with --mode all or --fallback-full: %s", err)
+		}
 		panic(err.Error())
 	}
+}
+
+// stateStore - the checkpoint.Store for --state, if set. Otherwise, a
+// SQLite output database records its own checkpoint, so that each run
+// applies the chunks following those already in the database, unless
+// the chunks to read are chosen by --mode, --after or --only
+func stateStore(logger *log.Logger, cfg config.Index) checkpoint.Store {
+	switch {
+	case State != "":
+		return checkpoint.NewFileStore(logger, State)
+	case cfg.Output.Format == config.SQLite && !modeFlagsSet():
+		return checkpoint.NewSQLiteStore(logger, cfg.Output.File)
+	default:
+		return nil
+	}
+}
+
+// modeFlagsSet - whether any flag choosing the chunks to read was passed
+func modeFlagsSet() bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "mode", "after", "only":
+			set = true
+		}
+	})
+	return set
+}
+
+// run - scan the planned index chunks to the configured output. Given a
+// checkpoint.Store, the run resumes after the stored checkpoint, and once
+// the output has flushed, records the newest chunk it fully emitted
+func run(ctx context.Context, logger *log.Logger, cfg config.Index, store checkpoint.Store) error {
+	p := plan{cfg: cfg}
+	if store != nil {
+		var err error
+		if p, err = resume(ctx, logger, cfg, store); err != nil {
+			return err
+		}
+	}
+
+	sink, err := output.NewSink(logger, p.cfg)
+	if err != nil {
+		return err
+	}
+
+	// chunks are handed to the output in plan order, up to a worker pool's
+	// worth ahead, and are complete once the output has ended them. Once
+	// all are scanned, closing the queue closes the output
+	work, cancelWork := context.WithCancel(ctx)
+	defer cancelWork()
+	chunks := make(chan *output.Chunk, Pool)
+	scanErrs := make(chan error, 1)
+	go func() {
+		scanErrs <- scanChunks(work, work, logger, p, chunks)
+		close(chunks)
+	}()
 
-	// make a queue to buffer records scanned from
-	// the various index chunks, and pass it to an
-	// output formatter according to CLI args
-	records := make(chan data.Record, 64)
-	out := output.ResolveFormat(logger, records, mavenCentralCfg)
+	// a cancelled output still flushes the chunks it ended. a failed
+	// one abandons the scans still waiting on it
+	outErr := output.Drive(ctx, sink, chunks, nil)
+	cancelWork()
+	scanErr := <-scanErrs
+
+	if p.tracker != nil && (outErr == nil || ctx.Err() != nil) {
+		if err := p.checkpoint(store); err != nil {
+			return err
+		}
+	}
+	if outErr != nil {
+		return outErr
+	}
+	if scanErr != nil {
+		return scanErr
+	}
+
+	// a rotated chain is only adopted once its full index is written
+	return readers.NewIndex(logger, nil, cfg).AdoptChain(ctx)
+}
+
+// plan - the configuration for a run, and when checkpointing,
+// the tracker following its chunks through to the output
+type plan struct {
+	cfg     config.Index
+	tracker *checkpoint.Tracker
+
+	// the ID of the last incremental chunk the full index incorporates
+	fullChunkID int
+}
+
+// chunkMeta - identify the chunk at target, to the output and checkpoint
+func (p plan) chunkMeta(target string) output.ChunkMeta {
+	meta := output.ChunkMeta{Target: target, ChunkID: p.fullChunkID, Full: true}
+	if chunkID, ok := p.cfg.ChunkID(target); ok {
+		meta.ChunkID, meta.Full = chunkID, false
+	}
+	if p.tracker != nil {
+		meta.IndexID, meta.ChainID = p.cfg.Meta.ID, p.cfg.Meta.ChainID
+	}
+
+	return meta
+}
+
+// checkpoint - save the newest chunk the run fully emitted, if any. Only
+// safe once the output has flushed every record it has consumed
+func (p plan) checkpoint(store checkpoint.Store) error {
+	// a SQLite output database commits its own checkpoint with each chunk
+	if _, ok := store.(checkpoint.SQLiteStore); ok {
+		return nil
+	}
+
+	if latest, ok := p.tracker.Latest(); ok {
+		return store.Save(latest)
+	}
+	return nil
+}
+
+// resume - plan the run after the stored checkpoint for the index's current
+// chain. Without one, as on the first run or once the chain has rotated,
+// the full index is read
+func resume(ctx context.Context, logger *log.Logger, cfg config.Index, store checkpoint.Store) (plan, error) {
+	if cfg.Source.Legacy {
+		return plan{}, errors.New("checkpoints are not supported for legacy indices")
+	}
+
+	// a rotated chain simply has no checkpoint yet
+	props, err := readers.NewIndex(logger, nil, cfg).Properties(ctx)
+	if err != nil && !readers.IsChainRotated(err) {
+		return plan{}, err
+	}
+	indexID, err := props.GetAsString(data.PropertyID)
+	if err != nil {
+		return plan{}, err
+	}
+	chainID, err := props.GetAsString(data.PropertyChainID)
+	if err != nil {
+		return plan{}, err
+	}
+	// a full index published without incrementals incorporates none
+	lastIncr, _, err := props.LastIncremental()
+	if err != nil {
+		return plan{}, err
+	}
+	cfg.Meta.ID, cfg.Meta.ChainID = indexID, chainID
+
+	latest, ok, err := store.Load(indexID, chainID)
+	if err != nil {
+		return plan{}, err
+	}
+
+	mode := config.Mode{Type: config.All}
+	if ok {
+		logger.Printf("Resuming index %s chain %s after chunk %d (%s)", indexID, chainID, latest.ChunkID, latest.Timestamp)
+		mode = latest.Mode()
+	} else {
+		logger.Printf("No checkpoint for index %s chain %s, reading the full index", indexID, chainID)
+	}
+	mode.Fallback = cfg.Mode.Fallback
+	cfg.Mode = mode
+
+	return plan{
+		cfg:         cfg,
+		tracker:     checkpoint.NewTracker(indexID, chainID),
+		fullChunkID: lastIncr,
+	}, nil
+}
+
+// scanChunks - enumerate the planned chunks, and scan them in a fixed-size
+// worker pool, handing each to the output in plan order. Once stop is done,
+// no further chunks are begun, while those already begun run until work is
+// done. A chunk is checkpointed once the output has ended it
+func scanChunks(stop, work context.Context, logger *log.Logger, p plan, chunks chan<- *output.Chunk) error {
+	// Fetch index properties and enumerate index chunks to be scanned.
+	// Legacy indices are a single archive, so there is nothing to enumerate
+	chunkNamesQueue := make(chan string, 16)
+	indexErrs := make(chan error, 1)
+	if p.cfg.Source.Legacy {
+		chunkNamesQueue <- p.cfg.ResolveTarget(".zip")
+		close(chunkNamesQueue)
+		indexErrs <- nil
+	} else {
+		index := readers.NewIndex(logger, chunkNamesQueue, p.cfg)
+		go func() {
+			indexErrs <- index.ReadContext(stop)
+		}()
+	}
 
-	// establish a fixed-size worker pool and feed resolved
-	// chunks to be scanned into the pool
 	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var scanErr error
 	chunkWorkerPool := make(chan struct{}, Pool)
 	for chunkName := range chunkNamesQueue {
 		target := chunkName
+
+		// workers are taken in plan order, the order the output ends chunks
+		// in, so a chunk waiting on the output never holds up an earlier one
+		chunkWorkerPool <- struct{}{}
+		if stop.Err() != nil {
+			logger.Printf("Chunk: skipped scan of chunk %s once stopped", target)
+			<-chunkWorkerPool
+			continue
+		}
+
+		chunk := output.NewChunk(p.chunkMeta(target))
+		select {
+		case chunks <- chunk:
+		case <-work.Done():
+			logger.Printf("Chunk: skipped scan of chunk %s once cancelled", target)
+			<-chunkWorkerPool
+			continue
+		}
+		if p.tracker != nil {
+			p.tracker.Plan(chunk.Meta.ChunkID)
+		}
 		wg.Add(1)
 
 		go func() {
@@ -113,27 +472,64 @@ func main() {
 				wg.Done()
 			}()
 
-			chunkWorkerPool <- struct{}{}
-			chunk := readers.NewChunk(logger, records, mavenCentralCfg, target, filterFn)
-			if err := chunk.Read(); err != nil {
-				if errors.Cause(err) == io.EOF {
-					logger.Printf("Chunk: EOF encountered for chunk: %s", target)
+			timestamp, err := scan(work, logger, p.cfg, target, chunk.Records())
+			chunk.Finish(timestamp, err)
+			if err == nil {
+				select {
+				case err = <-chunk.Ended():
+				case <-work.Done():
+					err = errors.Wrapf(work.Err(), "Chunk(%s): cancelled before written with cause", target)
+				}
+			}
+			if err != nil {
+				if work.Err() != nil {
+					logger.Printf("Chunk: cancelled scan of chunk %s: %s", target, err)
 					return
 				}
-				logger.Panicf(err.Error())
+
+				mu.Lock()
+				defer mu.Unlock()
+				if scanErr == nil {
+					scanErr = err
+				}
+				return
+			}
+
+			logger.Printf("Chunk: EOF encountered for chunk: %s", target)
+			if p.tracker != nil {
+				p.tracker.Complete(chunk.Meta.ChunkID, timestamp)
 			}
 		}()
 	}
+	wg.Wait()
 
-	// ensure that when all readers.Chunk goroutines are finished
-	// publishing data.Records, the output queue is closed. this
-	// will trigger the output formatter to complete and clean up.
-	go func() {
-		wg.Wait()
-		close(records)
-	}()
+	if err := <-indexErrs; err != nil {
+		return err
+	}
+	return scanErr
+}
 
-	if err := out.Write(); err != nil {
-		panic(err.Error())
+// scan - publish the chunk's records, returning the chunk timestamp once
+// every record has been consumed. Legacy indices carry no chunk timestamp
+func scan(ctx context.Context, logger *log.Logger, cfg config.Index, target string, records chan<- data.Record) (time.Time, error) {
+	if cfg.Source.Legacy {
+		err := readers.NewLegacyIndex(logger, records, cfg, target, filterFn).ReadContext(ctx)
+		if errors.Cause(err) == io.EOF {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
 	}
+
+	it := readers.OpenChunkContext(ctx, logger, cfg, target, filterFn)
+	defer it.Close()
+
+	for it.Next() {
+		select {
+		case <-ctx.Done():
+			return time.Time{}, errors.Wrapf(ctx.Err(), "Chunk(%s): cancelled with cause", target)
+		case records <- it.Record():
+		}
+	}
+
+	return it.Timestamp(), it.Err()
 }
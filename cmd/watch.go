@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/checkpoint"
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/output"
+	"github.com/elireisman/maven-index-reader-go/pkg/readers"
+
+	"github.com/pkg/errors"
+)
+
+// watch - the "watch" subcommand polls the index on an interval, writing the
+// records of chunks newer than the checkpoint to a single long-lived output,
+// and checkpointing after each poll. On SIGINT or SIGTERM, no further chunks
+// are begun, and those already begun are finished, flushed and checkpointed
+func watch(args []string) {
+	var interval time.Duration
+	watchFlags := flag.NewFlagSet("watch", flag.ExitOnError)
+	watchFlags.DurationVar(&interval, "interval", 15*time.Minute, "how often to poll the index for new chunks, like '15m'")
+	watchFlags.StringVar(&State, "state", "", "if set, resumes after the checkpoint recorded in this JSON state file, and records a new one after each poll. kept in memory if unset")
+	watchFlags.StringVar(&Format, "format", "log", formatUsage)
+	watchFlags.StringVar(&Out, "out", "", "if set, specifies the output file path. stdout if unset. the database file, for --format=sqlite")
+	csvFlags(watchFlags)
+	parquetFlags(watchFlags)
+	watchFlags.IntVar(&Pool, "pool", 4, "number of goroutines enabled to scan index chunks in parallel")
+	watchFlags.BoolVar(&Fallback, "fallback-full", false, "read the full index if chunks following the checkpoint are no longer published")
+	watchFlags.StringVar(&Cache, "cache", "", "if set, caches downloaded index files under this directory, revalidating on later polls")
+	watchFlags.BoolVar(&Sums, "verify-checksums", false, "verify each index file against its published .sha1/.md5 checksums")
+	watchFlags.StringVar(&Keyring, "keyring", "", "if set, verify each index file's detached .asc signature against this OpenPGP keyring file")
+	watchFlags.StringVar(&MetaStore, "meta", "", "if set, discovers the index and chain IDs on first poll, persisting them to this JSON file")
+	watchFlags.BoolVar(&Verbose, "verbose", false, "log config, skipped records, and progress verbosely")
+	watchFlags.Parse(args)
+
+	logger := log.Default()
+	if interval <= 0 {
+		logger.Fatalf("watch: --interval must be positive")
+	}
+
+	// chunk scans already begun are not cancelled by stop
+	stop, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	go func() {
+		// restores the default handling, so a second signal exits at once
+		<-stop.Done()
+		cancel()
+	}()
+
+	mavenCentralCfg := centralConfig()
+	if err := config.Validate(logger, mavenCentralCfg); err != nil {
+		panic(err.Error())
+	}
+
+	store := stateStore(logger, mavenCentralCfg)
+	if store == nil {
+		logger.Printf("watch: no --state set, checkpoints will be lost on exit")
+		store = checkpoint.NewMemoryStore()
+	}
+
+	// flushed Parquet rows are only readable from a completed file
+	mavenCentralCfg.Output.Parquet.RollOnFlush = true
+	sink, err := output.NewSink(logger, mavenCentralCfg)
+	if err != nil {
+		logger.Fatalf("watch: %s", err)
+	}
+
+	// chunks are handed to the long-lived output in plan order, as in a run
+	chunks := make(chan *output.Chunk, Pool)
+	flushes := make(chan output.FlushRequest)
+
+	var outErr error
+	outDone := make(chan struct{})
+	go func() {
+		defer close(outDone)
+		outErr = output.Drive(context.Background(), sink, chunks, flushes)
+	}()
+
+	// chunk scans already begun are finished even once stopped,
+	// unless the output stops, leaving them nowhere to go
+	work, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+	go func() {
+		<-outDone
+		cancelWork()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := poll(stop, work, logger, mavenCentralCfg, store, chunks, flushes, outDone); err != nil && stop.Err() == nil {
+			if readers.IsChainBroken(err) {
+				logger.Printf("watch: full re-ingest required, restart with --fallback-full: %s", err)
+			} else {
+				logger.Printf("watch: poll failed, retrying in %s: %s", interval, err)
+			}
+		}
+
+		select {
+		case <-stop.Done():
+			logger.Printf("watch: stopping")
+			close(chunks)
+			<-outDone
+			if outErr != nil {
+				panic(outErr.Error())
+			}
+			return
+
+		case <-outDone:
+			logger.Fatalf("watch: output stopped unexpectedly: %s", outErr)
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll - scan the chunks newer than the stored checkpoint to the running
+// output, then flush it, and checkpoint the newest chunk it has ended
+func poll(stop, work context.Context, logger *log.Logger, cfg config.Index, store checkpoint.Store,
+	chunks chan<- *output.Chunk, flushes chan<- output.FlushRequest, outDone <-chan struct{}) error {
+	p, err := resume(stop, logger, cfg, store)
+	if err != nil {
+		return err
+	}
+
+	scanErr := scanChunks(stop, work, logger, p, chunks)
+
+	// checkpoint whatever completed, even if the poll failed part way
+	req := make(output.FlushRequest, 1)
+	select {
+	case flushes <- req:
+	case <-outDone:
+		return errors.New("watch: output stopped before flush")
+	}
+	if err := <-req; err != nil {
+		return errors.Wrap(err, "watch: failed to flush output with cause")
+	}
+	if err := p.checkpoint(store); err != nil {
+		return err
+	}
+	if scanErr != nil {
+		return scanErr
+	}
+
+	// a rotated chain is only adopted once its full index is written
+	return readers.NewIndex(logger, nil, cfg).AdoptChain(work)
+}
@@ -0,0 +1,159 @@
+package lucene
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type fileEntry struct {
+	src    io.ReaderAt
+	offset int64
+	size   int64
+}
+
+// directory - the named files of a Lucene index, which may be plain
+// files on disk, or entries of a compound (.cfs or .cfx) file
+type directory struct {
+	files map[string]fileEntry
+}
+
+// open every plain file in the local directory at path
+func openDirectory(path string) (*directory, []io.Closer, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "lucene: failed to list index directory %s with cause", path)
+	}
+
+	dir := &directory{files: map[string]fileEntry{}}
+	var closers []io.Closer
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		f, err := os.Open(filepath.Join(path, entry.Name()))
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, errors.Wrapf(err, "lucene: failed to open index file %s with cause", entry.Name())
+		}
+		closers = append(closers, f)
+
+		info, err := f.Stat()
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, errors.Wrapf(err, "lucene: failed to stat index file %s with cause", entry.Name())
+		}
+
+		dir.files[entry.Name()] = fileEntry{src: f, size: info.Size()}
+	}
+
+	return dir, closers, nil
+}
+
+func (d *directory) exists(name string) bool {
+	_, found := d.files[name]
+	return found
+}
+
+func (d *directory) open(name string) (*dataInput, error) {
+	entry, found := d.files[name]
+	if !found {
+		return nil, errors.Errorf("lucene: index file %s not found", name)
+	}
+
+	return newDataInput(name, entry.src, entry.offset, entry.size), nil
+}
+
+// resolve the directory of files packed into the compound file. Entry names
+// are stripped of their segment name prefix, so "_0.fnm" becomes ".fnm"
+// https://lucene.apache.org/core/3_6_2/fileformats.html#Compound%20Files
+func (d *directory) compound(name string) (*directory, error) {
+	in, err := d.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// post-3.1 compound files begin with a negative format version,
+	// and their entry names are already stripped of the segment name
+	firstInt, err := in.readVInt()
+	if err != nil {
+		return nil, errors.Wrapf(err, "lucene: failed to read compound file %s header with cause", name)
+	}
+	count := firstInt
+	if firstInt < 0 {
+		if firstInt < -1 {
+			return nil, errors.Errorf("lucene: unsupported compound file %s format %d", name, firstInt)
+		}
+		if count, err = in.readVInt(); err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read compound file %s entry count with cause", name)
+		}
+	}
+
+	src := d.files[name]
+	out := &directory{files: map[string]fileEntry{}}
+	var prevID string
+	for ndx := int32(0); ndx < count; ndx++ {
+		offset, err := in.readInt64()
+		if err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read compound file %s entry offset with cause", name)
+		}
+		id, err := in.readString()
+		if err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read compound file %s entry name with cause", name)
+		}
+		id = stripSegmentName(id)
+
+		// each entry ends where the next begins
+		if ndx > 0 {
+			prev := out.files[prevID]
+			prev.size = offset - prev.offset
+			out.files[prevID] = prev
+		}
+		out.files[id] = fileEntry{src: src.src, offset: src.offset + offset}
+		prevID = id
+	}
+	if count > 0 {
+		last := out.files[prevID]
+		last.size = src.size - last.offset + src.offset
+		out.files[prevID] = last
+	}
+
+	for id, entry := range out.files {
+		if entry.size < 0 || entry.offset+entry.size > src.offset+src.size {
+			return nil, errors.Errorf("lucene: compound file %s entry %s is out of bounds", name, id)
+		}
+	}
+
+	return out, nil
+}
+
+// "_0.fnm" => ".fnm", "_0_1.del" => "_1.del"
+func stripSegmentName(name string) string {
+	if ndx := strings.IndexAny(name[min(1, len(name)):], "._"); ndx >= 0 {
+		return name[ndx+1:]
+	}
+
+	return name
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func closeAll(closers []io.Closer) error {
+	var out error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && out == nil {
+			out = err
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,307 @@
+package lucene
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"math"
+	"math/bits"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// field infos (.fnm) format versions and bits
+// https://lucene.apache.org/core/3_6_2/fileformats.html#Fields
+const (
+	fnmFormatOmitPositions = -3
+	fnmIsIndexed           = 0x01
+)
+
+// stored fields (.fdx, .fdt) format versions and bits
+const (
+	fieldsFormatUTF8LengthInBytes = 1
+	fieldsFormatCurrent           = 3
+
+	fieldIsTokenized   = 0x01
+	fieldIsBinary      = 0x02
+	fieldIsCompressed  = 0x04
+	fieldNumericMask   = 0x38
+	fieldNumericInt    = 1 << 3
+	fieldNumericLong   = 2 << 3
+	fieldNumericFloat  = 3 << 3
+	fieldNumericDouble = 4 << 3
+)
+
+// Field - a stored field of a Lucene document
+type Field struct {
+	Name       string
+	Value      string
+	Indexed    bool
+	Tokenized  bool
+	Compressed bool
+}
+
+type fieldInfo struct {
+	name    string
+	indexed bool
+}
+
+func readFieldInfos(in *dataInput) ([]fieldInfo, error) {
+	firstInt, err := in.readVInt()
+	if err != nil {
+		return nil, errors.Wrapf(err, "lucene: failed to read %s header with cause", in.name)
+	}
+
+	// explicit format versions are negative; older files begin with the field count
+	size := firstInt
+	if firstInt < 0 {
+		if firstInt < fnmFormatOmitPositions {
+			return nil, errors.Errorf("lucene: unsupported field infos %s format %d", in.name, firstInt)
+		}
+		if size, err = in.readVInt(); err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read %s field count with cause", in.name)
+		}
+	}
+
+	out := make([]fieldInfo, 0, size)
+	for ndx := int32(0); ndx < size; ndx++ {
+		name, err := in.readString()
+		if err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read %s field %d name with cause", in.name, ndx)
+		}
+		b, err := in.readByte()
+		if err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read %s field %d bits with cause", in.name, ndx)
+		}
+		out = append(out, fieldInfo{name: name, indexed: b&fnmIsIndexed != 0})
+	}
+
+	return out, nil
+}
+
+// fieldsReader - reads the stored fields of a segment's documents
+type fieldsReader struct {
+	infos  []fieldInfo
+	index  *dataInput
+	fields *dataInput
+	format int32
+
+	// the size of the .fdx header, and the segment's offset in a shared doc store
+	headerSize int64
+	docOffset  int64
+}
+
+func newFieldsReader(infos []fieldInfo, index, fields *dataInput, docStoreOffset, docCount int) (*fieldsReader, error) {
+	// the first version of .fdx had no header, but its first int is always 0
+	firstInt, err := index.readInt32()
+	if err != nil {
+		return nil, errors.Wrapf(err, "lucene: failed to read %s header with cause", index.name)
+	}
+	if firstInt > fieldsFormatCurrent {
+		return nil, errors.Errorf("lucene: unsupported stored fields %s format %d", index.name, firstInt)
+	}
+
+	out := &fieldsReader{
+		infos:  infos,
+		index:  index,
+		fields: fields,
+		format: firstInt,
+	}
+	if firstInt > 0 {
+		out.headerSize = 4
+	}
+	if docStoreOffset > 0 {
+		out.docOffset = int64(docStoreOffset)
+	}
+
+	if available := (index.length() - out.headerSize) / 8; available < out.docOffset+int64(docCount) {
+		return nil, errors.Errorf("lucene: %s indexes %d docs, expected at least %d", index.name, available, out.docOffset+int64(docCount))
+	}
+
+	return out, nil
+}
+
+func (fr *fieldsReader) document(docID int) ([]Field, error) {
+	if err := fr.index.seek(fr.headerSize + (int64(docID)+fr.docOffset)*8); err != nil {
+		return nil, err
+	}
+	position, err := fr.index.readInt64()
+	if err != nil {
+		return nil, err
+	}
+	if err := fr.fields.seek(position); err != nil {
+		return nil, err
+	}
+
+	numFields, err := fr.fields.readVInt()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Field, 0, numFields)
+	for ndx := int32(0); ndx < numFields; ndx++ {
+		fieldNumber, err := fr.fields.readVInt()
+		if err != nil {
+			return nil, err
+		}
+		if fieldNumber < 0 || int(fieldNumber) >= len(fr.infos) {
+			return nil, errors.Errorf("lucene: doc %d references unknown field number %d", docID, fieldNumber)
+		}
+		info := fr.infos[fieldNumber]
+
+		b, err := fr.fields.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		field := Field{
+			Name:       info.name,
+			Indexed:    info.indexed,
+			Tokenized:  b&fieldIsTokenized != 0,
+			Compressed: b&fieldIsCompressed != 0,
+		}
+
+		switch {
+		case b&fieldIsBinary != 0:
+			// no index record attributes are binary; skip over them
+			n, err := fr.fields.readVInt()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := fr.fields.readBytes(int(n)); err != nil {
+				return nil, err
+			}
+			continue
+
+		case b&fieldNumericMask != 0:
+			if field.Value, err = fr.readNumeric(b & fieldNumericMask); err != nil {
+				return nil, err
+			}
+
+		case field.Compressed:
+			n, err := fr.fields.readVInt()
+			if err != nil {
+				return nil, err
+			}
+			compressed, err := fr.fields.readBytes(int(n))
+			if err != nil {
+				return nil, err
+			}
+			if field.Value, err = inflate(compressed); err != nil {
+				return nil, errors.Wrapf(err, "lucene: failed to decompress doc %d field %s with cause", docID, info.name)
+			}
+
+		case fr.format >= fieldsFormatUTF8LengthInBytes:
+			if field.Value, err = fr.fields.readString(); err != nil {
+				return nil, err
+			}
+
+		default:
+			if field.Value, err = fr.fields.readModifiedString(); err != nil {
+				return nil, err
+			}
+		}
+
+		out = append(out, field)
+	}
+
+	return out, nil
+}
+
+func (fr *fieldsReader) readNumeric(kind byte) (string, error) {
+	switch kind {
+	case fieldNumericInt:
+		v, err := fr.fields.readInt32()
+		return strconv.FormatInt(int64(v), 10), err
+	case fieldNumericLong:
+		v, err := fr.fields.readInt64()
+		return strconv.FormatInt(v, 10), err
+	case fieldNumericFloat:
+		v, err := fr.fields.readInt32()
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(v))), 'g', -1, 32), err
+	case fieldNumericDouble:
+		v, err := fr.fields.readInt64()
+		return strconv.FormatFloat(math.Float64frombits(uint64(v)), 'g', -1, 64), err
+	}
+
+	return "", errors.Errorf("lucene: unknown numeric field type %d", kind>>3)
+}
+
+// pre-3.0 compressed fields are deflated with java.util.zip.Deflater
+func inflate(compressed []byte) (string, error) {
+	rdr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", err
+	}
+	defer rdr.Close()
+
+	out, err := io.ReadAll(rdr)
+	return string(out), err
+}
+
+// deletedDocs - the bit vector of a segment's deleted documents
+// https://lucene.apache.org/core/3_6_2/fileformats.html#Deleted%20Documents
+type deletedDocs []byte
+
+func (d deletedDocs) deleted(docID int) bool {
+	ndx := docID >> 3
+	return ndx < len(d) && d[ndx]&(1<<(uint(docID)&7)) != 0
+}
+
+func readDeletedDocs(in *dataInput) (deletedDocs, error) {
+	size, err := in.readInt32()
+	if err != nil {
+		return nil, err
+	}
+	// versioned bit vectors begin with -2, then the version
+	if size == -2 {
+		if _, err := in.readInt32(); err != nil {
+			return nil, err
+		}
+		if size, err = in.readInt32(); err != nil {
+			return nil, err
+		}
+	}
+
+	if size != -1 {
+		// count of set bits, then the bits
+		if _, err := in.readInt32(); err != nil {
+			return nil, err
+		}
+		b, err := in.readBytes(numBytes(size))
+		return deletedDocs(b), err
+	}
+
+	// sparse vectors are stored as "d-gaps" between non-zero bytes
+	if size, err = in.readInt32(); err != nil {
+		return nil, err
+	}
+	count, err := in.readInt32()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(deletedDocs, numBytes(size))
+	last := 0
+	for count > 0 {
+		gap, err := in.readVInt()
+		if err != nil {
+			return nil, err
+		}
+		last += int(gap)
+		if last < 0 || last >= len(out) {
+			return nil, errors.Errorf("lucene: %s deleted docs offset %d out of bounds", in.name, last)
+		}
+		if out[last], err = in.readByte(); err != nil {
+			return nil, err
+		}
+		count -= int32(bits.OnesCount8(out[last]))
+	}
+
+	return out, nil
+}
+
+func numBytes(size int32) int {
+	return int((size + 7) >> 3)
+}
@@ -0,0 +1,187 @@
+package lucene
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// dataInput - a seekable, buffered view of a Lucene index file, which
+// decodes the primitive types of the Lucene 2.x/3.x file formats
+// https://lucene.apache.org/core/3_6_2/fileformats.html#Primitive%20Types
+type dataInput struct {
+	name string
+	src  *io.SectionReader
+	buf  *bufio.Reader
+	pos  int64
+}
+
+func newDataInput(name string, r io.ReaderAt, offset, size int64) *dataInput {
+	src := io.NewSectionReader(r, offset, size)
+	return &dataInput{
+		name: name,
+		src:  src,
+		buf:  bufio.NewReader(src),
+	}
+}
+
+func (in *dataInput) length() int64 {
+	return in.src.Size()
+}
+
+func (in *dataInput) seek(pos int64) error {
+	if pos < 0 || pos > in.src.Size() {
+		return errors.Errorf("lucene(%s): seek to %d outside file of length %d", in.name, pos, in.src.Size())
+	}
+	if pos != in.pos {
+		in.buf.Reset(io.NewSectionReader(in.src, pos, in.src.Size()-pos))
+		in.pos = pos
+	}
+
+	return nil
+}
+
+func (in *dataInput) readBytes(n int) ([]byte, error) {
+	if n < 0 || int64(n) > in.src.Size()-in.pos {
+		return nil, errors.Errorf("lucene(%s): cannot read %d bytes at offset %d of file of length %d", in.name, n, in.pos, in.src.Size())
+	}
+
+	out := make([]byte, n)
+	read, err := io.ReadFull(in.buf, out)
+	in.pos += int64(read)
+	if err != nil {
+		return nil, errors.Wrapf(err, "lucene(%s): failed to read %d bytes at offset %d with cause", in.name, n, in.pos)
+	}
+
+	return out, nil
+}
+
+func (in *dataInput) readByte() (byte, error) {
+	b, err := in.buf.ReadByte()
+	if err != nil {
+		return 0, errors.Wrapf(err, "lucene(%s): failed to read byte at offset %d with cause", in.name, in.pos)
+	}
+	in.pos++
+
+	return b, nil
+}
+
+func (in *dataInput) readInt32() (int32, error) {
+	b, err := in.readBytes(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (in *dataInput) readInt64() (int64, error) {
+	b, err := in.readBytes(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+// variable-length integer, 7 bits per byte, low-order bytes first.
+// negative values are written as 5 byte unsigned 32-bit values
+func (in *dataInput) readVInt() (int32, error) {
+	v, err := in.readVLong()
+	return int32(uint32(v)), err
+}
+
+func (in *dataInput) readVLong() (int64, error) {
+	var out uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := in.readByte()
+		if err != nil {
+			return 0, err
+		}
+		out |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return int64(out), nil
+		}
+	}
+
+	return 0, errors.Errorf("lucene(%s): malformed variable-length integer at offset %d", in.name, in.pos)
+}
+
+// Lucene 2.4+ string: VInt length in bytes, followed by standard UTF-8
+func (in *dataInput) readString() (string, error) {
+	n, err := in.readVInt()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := in.readBytes(int(n))
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", errors.Errorf("lucene(%s): invalid UTF-8 string ending at offset %d", in.name, in.pos)
+	}
+
+	return string(b), nil
+}
+
+// pre-2.4 string: VInt length in UTF-16 chars, followed by "Java modified UTF-8"
+func (in *dataInput) readModifiedString() (string, error) {
+	n, err := in.readVInt()
+	if err != nil {
+		return "", err
+	}
+
+	chars := make([]uint16, 0, n)
+	for ndx := int32(0); ndx < n; ndx++ {
+		b, err := in.readByte()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case b&0x80 == 0:
+			chars = append(chars, uint16(b))
+		case b&0xe0 != 0xe0:
+			b2, err := in.readByte()
+			if err != nil {
+				return "", err
+			}
+			chars = append(chars, uint16(b&0x1f)<<6|uint16(b2&0x3f))
+		default:
+			trailing, err := in.readBytes(2)
+			if err != nil {
+				return "", err
+			}
+			chars = append(chars, uint16(b&0x0f)<<12|uint16(trailing[0]&0x3f)<<6|uint16(trailing[1]&0x3f))
+		}
+	}
+
+	return string(utf16.Decode(chars)), nil
+}
+
+func (in *dataInput) readStringStringMap() (map[string]string, error) {
+	n, err := in.readInt32()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string, n)
+	for ndx := int32(0); ndx < n; ndx++ {
+		key, err := in.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := in.readString()
+		if err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+
+	return out, nil
+}
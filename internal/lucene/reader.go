@@ -0,0 +1,164 @@
+// Package lucene reads the stored fields of Lucene 1.x-3.x indices, as
+// packed into legacy "nexus-maven-repository-index.zip" index files.
+// Only the files needed to recover stored documents are parsed.
+// https://lucene.apache.org/core/3_6_2/fileformats.html
+package lucene
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Reader - iterates over the live documents of every segment in an index
+type Reader struct {
+	dir      *directory
+	closers  []io.Closer
+	segments []SegmentInfo
+
+	// position of the next document to read
+	segment int
+	docID   int
+
+	current *fieldsReader
+	deleted deletedDocs
+}
+
+// Open - open the Lucene index in the local directory at path
+func Open(path string) (*Reader, error) {
+	dir, closers, err := openDirectory(path)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := readSegmentInfos(dir)
+	if err != nil {
+		closeAll(closers)
+		return nil, err
+	}
+
+	return &Reader{
+		dir:      dir,
+		closers:  closers,
+		segments: segments,
+	}, nil
+}
+
+// Segments - the metadata of each segment in the index
+func (r *Reader) Segments() []SegmentInfo {
+	return r.segments
+}
+
+// Next - obtain the stored fields of the next live document,
+// or io.EOF once every segment has been read
+func (r *Reader) Next() ([]Field, error) {
+	for r.segment < len(r.segments) {
+		info := r.segments[r.segment]
+		if r.current == nil {
+			if err := r.openSegment(info); err != nil {
+				return nil, errors.Wrapf(err, "lucene: failed to open segment %s with cause", info.Name)
+			}
+		}
+
+		for r.docID < info.DocCount {
+			docID := r.docID
+			r.docID++
+			if r.deleted.deleted(docID) {
+				continue
+			}
+
+			doc, err := r.current.document(docID)
+			if err != nil {
+				return nil, errors.Wrapf(err, "lucene: failed to read segment %s doc %d with cause", info.Name, docID)
+			}
+			return doc, nil
+		}
+
+		r.segment++
+		r.docID = 0
+		r.current = nil
+		r.deleted = nil
+	}
+
+	return nil, io.EOF
+}
+
+// Close - release the index files
+func (r *Reader) Close() error {
+	closers := r.closers
+	r.closers = nil
+	return closeAll(closers)
+}
+
+func (r *Reader) openSegment(info SegmentInfo) error {
+	// field infos are in the segment's compound file, if it has one
+	segmentDir := r.dir
+	fnm := info.Name + ".fnm"
+	if info.IsCompound == 1 {
+		cfs, err := r.dir.compound(info.Name + ".cfs")
+		if err != nil {
+			return err
+		}
+		segmentDir = cfs
+		fnm = ".fnm"
+	}
+
+	fnmIn, err := segmentDir.open(fnm)
+	if err != nil {
+		return err
+	}
+	infos, err := readFieldInfos(fnmIn)
+	if err != nil {
+		return err
+	}
+
+	// stored fields may live in a doc store shared with other segments
+	storeDir := segmentDir
+	prefix := ""
+	if info.IsCompound != 1 {
+		prefix = info.Name
+	}
+	if info.DocStoreOffset != -1 {
+		storeDir = r.dir
+		prefix = info.DocStoreSegment
+		if info.DocStoreIsCompound {
+			if storeDir, err = r.dir.compound(info.DocStoreSegment + ".cfx"); err != nil {
+				return err
+			}
+			prefix = ""
+		}
+	}
+
+	fdx, err := storeDir.open(prefix + ".fdx")
+	if err != nil {
+		return err
+	}
+	fdt, err := storeDir.open(prefix + ".fdt")
+	if err != nil {
+		return err
+	}
+	if r.current, err = newFieldsReader(infos, fdx, fdt, info.DocStoreOffset, info.DocCount); err != nil {
+		return err
+	}
+
+	// deleted docs are never packed into compound files
+	delFile := ""
+	switch {
+	case info.DelGen > 0:
+		delFile = info.Name + "_" + strconv.FormatInt(info.DelGen, 36) + ".del"
+	case info.DelGen == 0 && r.dir.exists(info.Name+".del"):
+		delFile = info.Name + ".del"
+	}
+	if delFile != "" {
+		delIn, err := r.dir.open(delFile)
+		if err != nil {
+			return err
+		}
+		if r.deleted, err = readDeletedDocs(delIn); err != nil {
+			return errors.Wrapf(err, "lucene: failed to read deleted docs %s with cause", delFile)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package lucene
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, path string) [][]Field {
+	r, err := Open(path)
+	require.NoError(t, err)
+	defer r.Close()
+
+	var docs [][]Field
+	for {
+		doc, err := r.Next()
+		if err == io.EOF {
+			return docs
+		}
+		require.NoError(t, err)
+		docs = append(docs, doc)
+	}
+}
+
+func values(doc []Field) map[string]string {
+	out := map[string]string{}
+	for _, f := range doc {
+		out[f.Name] = f.Value
+	}
+	return out
+}
+
+func TestReaderLucene3(t *testing.T) {
+	r, err := Open("testdata/v3")
+	require.NoError(t, err)
+	segments := r.Segments()
+	require.NoError(t, r.Close())
+
+	require.Len(t, segments, 2)
+	require.Equal(t, "_0", segments[0].Name)
+	require.Equal(t, int8(1), segments[0].IsCompound)
+	require.Equal(t, "_1", segments[1].Name)
+	require.Equal(t, int64(1), segments[1].DelGen)
+
+	docs := readAll(t, "testdata/v3")
+
+	// one of the 7 documents is marked deleted
+	require.Len(t, docs, 6)
+	require.Equal(t, "NexusIndex", values(docs[0])["DESCRIPTOR"])
+	require.Equal(t, "org.example|widget|1.0|NA|jar", values(docs[1])["u"])
+	require.Equal(t, "Gadget €", values(docs[2])["n"])
+	require.Equal(t, "org.example|gizmo|0.1|NA|jar", values(docs[3])["del"])
+	require.Equal(t, "org.example", values(docs[4])["allGroupsList"])
+	require.Equal(t, "org", values(docs[5])["rootGroupsList"])
+
+	for _, f := range docs[1] {
+		switch f.Name {
+		case "u":
+			require.True(t, f.Indexed)
+			require.False(t, f.Tokenized)
+		case "n":
+			require.True(t, f.Tokenized)
+		case "i":
+			require.False(t, f.Indexed)
+		}
+	}
+}
+
+func TestReaderLucene2SharedDocStore(t *testing.T) {
+	docs := readAll(t, "testdata/v2")
+
+	require.Len(t, docs, 2)
+	widget := values(docs[0])
+	require.Equal(t, "org.example|widget|1.0|NA|jar", widget["u"])
+	require.Equal(t, "A widget", widget["d"])
+	require.Equal(t, "Gadget €", values(docs[1])["n"])
+
+	for _, f := range docs[0] {
+		if f.Name == "d" {
+			require.True(t, f.Compressed)
+		}
+	}
+}
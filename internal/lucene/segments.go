@@ -0,0 +1,220 @@
+package lucene
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// segments file format versions, from SegmentInfos.java
+// https://lucene.apache.org/core/3_6_2/fileformats.html#Segments%20File
+const (
+	formatLockless        = -2
+	formatSingleNormFile  = -3
+	formatSharedDocStore  = -4
+	formatDelCount        = -6
+	formatHasProx         = -7
+	formatDiagnostics     = -9
+	formatHasVectors      = -10
+	format3_1             = -11
+	newestFormatSupported = format3_1
+)
+
+// SegmentInfo - the subset of a segment's metadata needed to read its stored fields
+type SegmentInfo struct {
+	Name     string
+	DocCount int
+
+	// generation of the deleted docs file: -1 for none, 0 to check the directory
+	DelGen int64
+
+	// the shared doc store holding this segment's stored fields, if any
+	DocStoreOffset     int
+	DocStoreSegment    string
+	DocStoreIsCompound bool
+
+	// 1 if packed in a compound file, -1 if not, 0 to check the directory
+	IsCompound int8
+}
+
+// resolve the most recent segments file in the directory: "segments_N", with
+// the largest base 36 generation N, or "segments" for pre-lockless indices
+func latestSegmentsFile(dir *directory) (string, error) {
+	latest := ""
+	latestGen := int64(-1)
+	for name := range dir.files {
+		if name == "segments" && latestGen < 0 {
+			latest, latestGen = name, 0
+			continue
+		}
+		if !strings.HasPrefix(name, "segments_") {
+			continue
+		}
+
+		gen, err := strconv.ParseInt(strings.TrimPrefix(name, "segments_"), 36, 64)
+		if err == nil && gen > latestGen {
+			latest, latestGen = name, gen
+		}
+	}
+
+	if latest == "" {
+		return "", errors.New("lucene: no segments file found in index")
+	}
+
+	return latest, nil
+}
+
+func readSegmentInfos(dir *directory) ([]SegmentInfo, error) {
+	name, err := latestSegmentsFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := dir.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	format, err := in.readInt32()
+	if err != nil {
+		return nil, errors.Wrapf(err, "lucene: failed to read %s format with cause", name)
+	}
+	if format < newestFormatSupported {
+		return nil, errors.Errorf("lucene: unsupported segments file %s format %d; only Lucene 1.x-3.x indices are supported", name, format)
+	}
+
+	// explicit format versions are negative; older files begin with the name counter
+	if format < 0 {
+		if _, err := in.readInt64(); err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read %s version with cause", name)
+		}
+		if _, err := in.readInt32(); err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read %s name counter with cause", name)
+		}
+	}
+
+	count, err := in.readInt32()
+	if err != nil {
+		return nil, errors.Wrapf(err, "lucene: failed to read %s segment count with cause", name)
+	}
+
+	out := make([]SegmentInfo, 0, count)
+	for ndx := int32(0); ndx < count; ndx++ {
+		info, err := readSegmentInfo(in, format)
+		if err != nil {
+			return nil, errors.Wrapf(err, "lucene: failed to read %s segment %d with cause", name, ndx)
+		}
+
+		// pre-lockless indices record nothing about compound files
+		if info.IsCompound == 0 {
+			info.IsCompound = -1
+			if dir.exists(info.Name + ".cfs") {
+				info.IsCompound = 1
+			}
+		}
+		out = append(out, info)
+	}
+
+	return out, nil
+}
+
+// mirrors the SegmentInfo(Directory, int, IndexInput) constructor of Lucene 3.x
+func readSegmentInfo(in *dataInput, format int32) (SegmentInfo, error) {
+	var out SegmentInfo
+
+	if format <= format3_1 {
+		// the Lucene version that wrote the segment
+		if _, err := in.readString(); err != nil {
+			return out, err
+		}
+	}
+
+	name, err := in.readString()
+	if err != nil {
+		return out, err
+	}
+	out.Name = name
+
+	docCount, err := in.readInt32()
+	if err != nil {
+		return out, err
+	}
+	out.DocCount = int(docCount)
+
+	out.DocStoreOffset = -1
+	out.DocStoreSegment = name
+	if format > formatLockless {
+		// pre-lockless indices must check the directory for everything else
+		return out, nil
+	}
+
+	if out.DelGen, err = in.readInt64(); err != nil {
+		return out, err
+	}
+
+	if format <= formatSharedDocStore {
+		offset, err := in.readInt32()
+		if err != nil {
+			return out, err
+		}
+		out.DocStoreOffset = int(offset)
+
+		if offset != -1 {
+			if out.DocStoreSegment, err = in.readString(); err != nil {
+				return out, err
+			}
+			b, err := in.readByte()
+			if err != nil {
+				return out, err
+			}
+			out.DocStoreIsCompound = b == 1
+		}
+	}
+
+	if format <= formatSingleNormFile {
+		// has single norm file
+		if _, err := in.readByte(); err != nil {
+			return out, err
+		}
+	}
+
+	numNormGen, err := in.readInt32()
+	if err != nil {
+		return out, err
+	}
+	for ndx := int32(0); ndx < numNormGen; ndx++ {
+		if _, err := in.readInt64(); err != nil {
+			return out, err
+		}
+	}
+
+	isCompound, err := in.readByte()
+	if err != nil {
+		return out, err
+	}
+	out.IsCompound = int8(isCompound)
+
+	if format <= formatDelCount {
+		if _, err := in.readInt32(); err != nil {
+			return out, err
+		}
+	}
+	if format <= formatHasProx {
+		if _, err := in.readByte(); err != nil {
+			return out, err
+		}
+	}
+	if format <= formatDiagnostics {
+		if _, err := in.readStringStringMap(); err != nil {
+			return out, err
+		}
+	}
+	if format <= formatHasVectors {
+		if _, err := in.readByte(); err != nil {
+			return out, err
+		}
+	}
+
+	return out, nil
+}
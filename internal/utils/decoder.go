@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strconv"
 	"time"
+	"unicode/utf16"
 
 	"github.com/pkg/errors"
 )
@@ -19,12 +20,17 @@ func GetString(strBuf []byte) (string, error) {
 		if (strBuf[ndx] & 0xe0) == 0xe0 {
 			// if the first byte begins with 1110 then there will be 3 bytes to decode
 			if ndx+2 < strByteLen && legalTrailingByte(strBuf[ndx+1]) && legalTrailingByte(strBuf[ndx+2]) {
-				ch := rune(((strBuf[ndx] & 0x1f) << 12) |
-					((strBuf[ndx+1] & 0x3f) << 6) |
-					(strBuf[ndx+2] & 0x3f))
+				ch := (rune(strBuf[ndx]&0x0f) << 12) |
+					(rune(strBuf[ndx+1]&0x3f) << 6) |
+					rune(strBuf[ndx+2]&0x3f)
 				out = append(out, ch)
 				ndx += 3
 			} else {
+				if ndx+2 >= strByteLen {
+					return "", errors.Errorf(
+						"GetString: truncated length 3 char at index %d of buffer of length %d: %v",
+						ndx, strByteLen, strBuf[ndx:])
+				}
 				if strBuf[ndx+1] == 0 || strBuf[ndx+2] == 0 {
 					return "", errors.Errorf(
 						"GetString: unexpected 0 bytes after index %d of buffer of length %d: %v",
@@ -37,10 +43,15 @@ func GetString(strBuf []byte) (string, error) {
 		} else if (strBuf[ndx] & 0xc0) == 0xc0 {
 			// if the first byte begins with 1100 then there will be 2 bytes to decode
 			if ndx+1 < strByteLen && legalTrailingByte(strBuf[ndx+1]) {
-				ch := rune(((strBuf[ndx] & 0x1f) << 6) | (strBuf[ndx+1] & 0x3f))
+				ch := (rune(strBuf[ndx]&0x1f) << 6) | rune(strBuf[ndx+1]&0x3f)
 				out = append(out, ch)
 				ndx += 2
 			} else {
+				if ndx+1 >= strByteLen {
+					return "", errors.Errorf(
+						"GetString: truncated length 2 char at index %d of buffer of length %d: %v",
+						ndx, strByteLen, strBuf[ndx:])
+				}
 				if strBuf[ndx+1] == 0 {
 					return "", errors.Errorf(
 						"GetString: unexpected 0 bytes after index %d of buffer of length %d: %v",
@@ -74,7 +85,17 @@ func GetString(strBuf []byte) (string, error) {
 		}
 	}
 
-	return string(out), nil
+	// supplementary characters are encoded as a surrogate pair of
+	// 3 byte chars, which must be recombined into a single rune
+	return string(utf16.Decode(toUTF16(out))), nil
+}
+
+func toUTF16(runes []rune) []uint16 {
+	out := make([]uint16, 0, len(runes))
+	for _, r := range runes {
+		out = append(out, uint16(r))
+	}
+	return out
 }
 
 func legalTrailingByte(b byte) bool {
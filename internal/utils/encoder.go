@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"time"
+	"unicode/utf16"
+)
+
+// Encode Go UTF-8 string into a byte buffer in "Java modified UTF-8" encoding. The inverse of GetString.
+// See DataOutput#writeUTF: https://docs.oracle.com/javase/6/docs/api/java/io/DataOutput.html#writeUTF%28java.lang.String%29
+func PutString(s string) []byte {
+	// Java strings are UTF-16; supplementary characters are
+	// encoded as a surrogate pair of 3 byte chars
+	chars := utf16.Encode([]rune(s))
+
+	out := make([]byte, 0, len(chars))
+	for _, ch := range chars {
+		switch {
+		case ch >= 0x0001 && ch <= 0x007f:
+			// well-formed 1-byte char of the form 0xxxxxxx
+			out = append(out, byte(ch))
+		case ch <= 0x07ff:
+			// 2-byte char of the form 110xxxxx 10xxxxxx, including
+			// the 0 char, which is never encoded as a 0 byte
+			out = append(out,
+				byte(0xc0|((ch>>6)&0x1f)),
+				byte(0x80|(ch&0x3f)))
+		default:
+			// 3-byte char of the form 1110xxxx 10xxxxxx 10xxxxxx
+			out = append(out,
+				byte(0xe0|((ch>>12)&0x0f)),
+				byte(0x80|((ch>>6)&0x3f)),
+				byte(0x80|(ch&0x3f)))
+		}
+	}
+
+	return out
+}
+
+// FormatTimestamp - the inverse of GetTimestamp. Example output:
+// 20220801003457.736 +0000
+//
+// Mimics functionality of:
+// formatter = new java.text.SimpleDateFormat("yyyyMMddHHmmss.SSS Z");
+// formatter.setTimeZone(java.util.TimeZone.getTimeZone("GMT"));
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format("20060102150405.000 -0700")
+}
@@ -46,6 +46,13 @@ func readUTF8String(r io.Reader, strByteLen int) (string, error) {
 		}
 	}
 
+	// the stream ended before the expected buffer was filled
+	if bytesRead < strByteLen {
+		return "", errors.Wrapf(io.ErrUnexpectedEOF,
+			"readUTF8String: failed to read expected buffer of size %d (got %d) with cause",
+			strByteLen, bytesRead)
+	}
+
 	// parse the buffer into std UTF-8. if no parse error,
 	// conserve possible reader io.EOF for caller
 	s, sErr := GetString(strBuf)
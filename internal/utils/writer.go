@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// write a variable-length string in "Java modified UTF-8" encoding
+func WriteString(w io.Writer, s string) error {
+	buf := PutString(s)
+	if len(buf) > math.MaxUint16 {
+		return errors.Errorf("WriteString: encoded string length %d exceeds max of %d bytes", len(buf), math.MaxUint16)
+	}
+
+	if err := WriteUint16(w, uint16(len(buf))); err != nil {
+		return errors.Wrap(err, "WriteString: failed to write string length uint16 with cause")
+	}
+
+	return writeAll(w, buf)
+}
+
+// write a variable-length string in "Java modified UTF-8" encoding.
+func WriteLargeString(w io.Writer, s string) error {
+	buf := PutString(s)
+	if len(buf) > math.MaxInt32 {
+		return errors.Errorf("WriteLargeString: encoded string length %d exceeds max of %d bytes", len(buf), math.MaxInt32)
+	}
+
+	if err := WriteInt32(w, int32(len(buf))); err != nil {
+		return errors.Wrap(err, "WriteLargeString: failed to write string length int32 with cause")
+	}
+
+	return writeAll(w, buf)
+}
+
+// WriteByte -
+func WriteByte(w io.Writer, b byte) error {
+	return writeAll(w, []byte{b})
+}
+
+// WriteUint16 -
+func WriteUint16(w io.Writer, v uint16) error {
+	var arr [2]byte
+	binary.BigEndian.PutUint16(arr[:], v)
+	return writeAll(w, arr[:])
+}
+
+// WriteInt32 -
+func WriteInt32(w io.Writer, v int32) error {
+	var arr [4]byte
+	binary.BigEndian.PutUint32(arr[:], uint32(v))
+	return writeAll(w, arr[:])
+}
+
+// WriteInt64 -
+func WriteInt64(w io.Writer, v int64) error {
+	var arr [8]byte
+	binary.BigEndian.PutUint64(arr[:], uint64(v))
+	return writeAll(w, arr[:])
+}
+
+func writeAll(w io.Writer, buf []byte) error {
+	n, err := w.Write(buf)
+	if err != nil {
+		return errors.Wrapf(err, "writeAll: failed to write buffer of size %d (wrote %d) with cause", len(buf), n)
+	}
+	if n != len(buf) {
+		return errors.Errorf("writeAll: expected to write %d bytes, wrote: %d", len(buf), n)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutString(t *testing.T) {
+	// 1, 2 and 3 byte chars, the 0 char, and a supplementary char
+	payload := "Hello ý € \x00 \U0001f600 World"
+
+	encoded := PutString(payload)
+	require.NotContains(t, encoded, byte(0))
+	require.Equal(t, []byte{0xc0, 0x80}, PutString("\x00"))
+	require.Equal(t, []byte{0xe2, 0x82, 0xac}, PutString("€"))
+	require.Equal(t, []byte{0xed, 0xa0, 0xbd, 0xed, 0xb8, 0x80}, PutString("\U0001f600"))
+
+	got, err := GetString(encoded)
+	require.NoError(t, err)
+	require.Equal(t, payload, got)
+}
+
+func TestWriteString(t *testing.T) {
+	payload := "Hello ý World"
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteString(&buffer, payload))
+	require.NoError(t, WriteLargeString(&buffer, payload))
+
+	got, err := ReadString(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, payload, got)
+
+	got, err = ReadLargeString(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, payload, got)
+}
+
+func TestWriteInts(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, WriteByte(&buffer, 0x7f))
+	require.NoError(t, WriteUint16(&buffer, 56100))
+	require.NoError(t, WriteInt32(&buffer, -618341596))
+	require.NoError(t, WriteInt64(&buffer, -2655756928899818716))
+
+	b, err := ReadByte(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x7f), b)
+
+	u16, err := ReadUint16(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, uint16(56100), u16)
+
+	i32, err := ReadInt32(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, int32(-618341596), i32)
+
+	i64, err := ReadInt64(&buffer)
+	require.NoError(t, err)
+	require.Equal(t, int64(-2655756928899818716), i64)
+}
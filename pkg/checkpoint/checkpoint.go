@@ -0,0 +1,120 @@
+package checkpoint
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+)
+
+// Checkpoint - the last chunk of an index fully emitted to, and flushed by,
+// the output. A chain ID is only meaningful to the index that published it,
+// and chunk IDs only within their chain, so checkpoints are keyed by both
+type Checkpoint struct {
+	IndexID string `json:"indexId"`
+	ChainID string `json:"chainId"`
+
+	// ID and timestamp of the last chunk emitted. For a full index,
+	// the ID of the last incremental chunk it incorporates
+	ChunkID   int       `json:"chunkId"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// when the checkpoint was recorded
+	Updated time.Time `json:"updated"`
+}
+
+// Mode - the config.Mode resuming after the checkpoint
+func (c Checkpoint) Mode() config.Mode {
+	return config.Mode{
+		Type:  config.AfterChunk,
+		After: strconv.Itoa(c.ChunkID),
+	}
+}
+
+// Store - contract for persisting Checkpoints between runs
+type Store interface {
+	// Load - the checkpoint for the index and chain,
+	// or false if none has been recorded
+	Load(indexID, chainID string) (Checkpoint, bool, error)
+
+	// Save - record the checkpoint, replacing any
+	// previous one for the same index and chain
+	Save(c Checkpoint) error
+}
+
+// Tracker - follows the chunks planned for a run through to completion, as
+// chunk scans can finish in any order. The latest checkpoint is the newest
+// completed chunk not preceded by any chunk still pending
+type Tracker struct {
+	indexID string
+	chainID string
+
+	mu        sync.Mutex
+	pending   map[int]bool
+	completed map[int]time.Time
+}
+
+func NewTracker(indexID, chainID string) *Tracker {
+	return &Tracker{
+		indexID:   indexID,
+		chainID:   chainID,
+		pending:   map[int]bool{},
+		completed: map[int]time.Time{},
+	}
+}
+
+// Plan - register a chunk that will be scanned
+func (t *Tracker) Plan(chunkID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending[chunkID] = true
+}
+
+// Complete - register that every record from the chunk has been consumed
+// by the output. It is only safe to checkpoint once the output has flushed
+func (t *Tracker) Complete(chunkID int, timestamp time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending, chunkID)
+	t.completed[chunkID] = timestamp
+}
+
+// Latest - the checkpoint to record, or false if no chunk
+// has completed without an older chunk still pending
+func (t *Tracker) Latest() (Checkpoint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out Checkpoint
+	found := false
+	for chunkID, timestamp := range t.completed {
+		if found && chunkID <= out.ChunkID {
+			continue
+		}
+		if t.pendingBefore(chunkID) {
+			continue
+		}
+
+		out = Checkpoint{
+			IndexID:   t.indexID,
+			ChainID:   t.chainID,
+			ChunkID:   chunkID,
+			Timestamp: timestamp,
+		}
+		found = true
+	}
+
+	return out, found
+}
+
+func (t *Tracker) pendingBefore(chunkID int) bool {
+	for pendingID := range t.pending {
+		if pendingID < chunkID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package checkpoint
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	ts := time.UnixMilli(1243533418015).UTC()
+	tracker := NewTracker("central", "1318453614498")
+
+	_, ok := tracker.Latest()
+	require.False(t, ok)
+
+	for _, chunkID := range []int{5, 6, 7} {
+		tracker.Plan(chunkID)
+	}
+
+	// chunks may complete out of order, but only those
+	// not preceded by a pending chunk can be checkpointed
+	tracker.Complete(6, ts.Add(time.Hour))
+	_, ok = tracker.Latest()
+	require.False(t, ok)
+
+	tracker.Complete(5, ts)
+	latest, ok := tracker.Latest()
+	require.True(t, ok)
+	require.Equal(t, Checkpoint{
+		IndexID:   "central",
+		ChainID:   "1318453614498",
+		ChunkID:   6,
+		Timestamp: ts.Add(time.Hour),
+	}, latest)
+
+	tracker.Complete(7, ts.Add(2*time.Hour))
+	latest, ok = tracker.Latest()
+	require.True(t, ok)
+	require.Equal(t, 7, latest.ChunkID)
+	require.Equal(t, config.Mode{Type: config.AfterChunk, After: "7"}, latest.Mode())
+}
+
+func TestFileStore(t *testing.T) {
+	logger := log.Default()
+	path := filepath.Join(t.TempDir(), "state", "checkpoints.json")
+	store := NewFileStore(logger, path)
+
+	_, ok, err := store.Load("central", "1318453614498")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ts := time.UnixMilli(1243533418015).UTC()
+	first := Checkpoint{IndexID: "central", ChainID: "1318453614498", ChunkID: 5, Timestamp: ts}
+	require.NoError(t, store.Save(first))
+
+	// checkpoints are keyed by both index and chain ID
+	other := Checkpoint{IndexID: "central", ChainID: "1318453619999", ChunkID: 1, Timestamp: ts}
+	require.NoError(t, store.Save(other))
+
+	latest, ok, err := store.Load("central", "1318453614498")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 5, latest.ChunkID)
+	require.True(t, ts.Equal(latest.Timestamp))
+	require.False(t, latest.Updated.IsZero())
+
+	// saving replaces the previous checkpoint for the same index and chain
+	first.ChunkID = 6
+	require.NoError(t, NewFileStore(logger, path).Save(first))
+
+	latest, ok, err = NewFileStore(logger, path).Load("central", "1318453614498")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 6, latest.ChunkID)
+
+	latest, ok, err = store.Load("central", "1318453619999")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 1, latest.ChunkID)
+
+	_, ok, err = store.Load("apache-snapshots-local", "1318453614498")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = os.Stat(path + ".tmp")
+	require.True(t, os.IsNotExist(err))
+
+	// a corrupt state file is reported, rather than silently restarting
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, _, err = store.Load("central", "1318453614498")
+	require.Error(t, err)
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	_, ok, err := store.Load("central", "1318453614498")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, store.Save(Checkpoint{IndexID: "central", ChainID: "1318453614498", ChunkID: 5}))
+	require.NoError(t, store.Save(Checkpoint{IndexID: "central", ChainID: "1318453614498", ChunkID: 6}))
+
+	latest, ok, err := store.Load("central", "1318453614498")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 6, latest.ChunkID)
+	require.False(t, latest.Updated.IsZero())
+
+	_, ok, err = store.Load("central", "1318453619999")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestSQLiteStore(t *testing.T) {
+	logger := log.Default()
+	path := filepath.Join(t.TempDir(), "db", "central.db")
+	store := NewSQLiteStore(logger, path)
+
+	_, ok, err := store.Load("central", "1318453614498")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ts := time.UnixMilli(1243533418015).UTC()
+	first := Checkpoint{IndexID: "central", ChainID: "1318453614498", ChunkID: 5, Timestamp: ts}
+	require.NoError(t, store.Save(first))
+	require.NoError(t, store.Save(Checkpoint{IndexID: "central", ChainID: "1318453619999", ChunkID: 1, Timestamp: ts}))
+
+	first.ChunkID = 6
+	require.NoError(t, NewSQLiteStore(logger, path).Save(first))
+
+	latest, ok, err := NewSQLiteStore(logger, path).Load("central", "1318453614498")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 6, latest.ChunkID)
+	require.True(t, ts.Equal(latest.Timestamp))
+	require.False(t, latest.Updated.IsZero())
+
+	latest, ok, err = store.Load("central", "1318453619999")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 1, latest.ChunkID)
+}
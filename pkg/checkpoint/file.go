@@ -0,0 +1,112 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FileStore - persists Checkpoints for any number of
+// indices and chains to a single JSON state file
+type FileStore struct {
+	path   string
+	logger *log.Logger
+}
+
+// the FileStore's JSON document
+type stateFile struct {
+	Checkpoints []Checkpoint `json:"checkpoints"`
+}
+
+func NewFileStore(l *log.Logger, path string) FileStore {
+	return FileStore{
+		path:   path,
+		logger: l,
+	}
+}
+
+// Load -
+func (fs FileStore) Load(indexID, chainID string) (Checkpoint, bool, error) {
+	state, err := fs.read()
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+
+	for _, c := range state.Checkpoints {
+		if c.IndexID == indexID && c.ChainID == chainID {
+			return c, true, nil
+		}
+	}
+
+	return Checkpoint{}, false, nil
+}
+
+// Save - the state file is replaced once the new one is complete,
+// so an interrupted Save leaves the previous checkpoints intact
+func (fs FileStore) Save(c Checkpoint) error {
+	state, err := fs.read()
+	if err != nil {
+		return err
+	}
+
+	if c.Updated.IsZero() {
+		c.Updated = time.Now().UTC()
+	}
+
+	replaced := false
+	for i, prev := range state.Checkpoints {
+		if prev.IndexID == c.IndexID && prev.ChainID == c.ChainID {
+			state.Checkpoints[i] = c
+			replaced = true
+		}
+	}
+	if !replaced {
+		state.Checkpoints = append(state.Checkpoints, c)
+	}
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "FileStore: failed to encode checkpoints with cause")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fs.path), 0o755); err != nil {
+		return errors.Wrapf(err, "FileStore: failed to create directory for %s with cause", fs.path)
+	}
+
+	tmp := fs.path + ".tmp"
+	if err := os.WriteFile(tmp, append(content, '\n'), 0o644); err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "FileStore: failed to write %s with cause", tmp)
+	}
+	if err := os.Rename(tmp, fs.path); err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "FileStore: failed to move %s into place with cause", fs.path)
+	}
+
+	fs.logger.Printf("FileStore: saved checkpoint for index %s chain %s at chunk %d (%s)",
+		c.IndexID, c.ChainID, c.ChunkID, c.Timestamp)
+	return nil
+}
+
+// read - the state file's checkpoints, or none if it doesn't exist yet
+func (fs FileStore) read() (stateFile, error) {
+	var state stateFile
+
+	content, err := os.ReadFile(fs.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return state, errors.Wrapf(err, "FileStore: failed to read %s with cause", fs.path)
+	}
+
+	if err := json.Unmarshal(content, &state); err != nil {
+		return state, errors.Wrapf(err, "FileStore: failed to parse %s with cause", fs.path)
+	}
+
+	return state, nil
+}
@@ -0,0 +1,40 @@
+package checkpoint
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore - keeps Checkpoints for the life of the process only,
+// as for a long-running watch with no state file to resume from
+type MemoryStore struct {
+	mu          sync.Mutex
+	checkpoints map[[2]string]Checkpoint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		checkpoints: map[[2]string]Checkpoint{},
+	}
+}
+
+// Load -
+func (ms *MemoryStore) Load(indexID, chainID string) (Checkpoint, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	c, ok := ms.checkpoints[[2]string{indexID, chainID}]
+	return c, ok, nil
+}
+
+// Save -
+func (ms *MemoryStore) Save(c Checkpoint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if c.Updated.IsZero() {
+		c.Updated = time.Now().UTC()
+	}
+	ms.checkpoints[[2]string{c.IndexID, c.ChainID}] = c
+	return nil
+}
@@ -0,0 +1,130 @@
+package checkpoint
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// SQLiteMetadataSchema - the table SQLiteStore keeps its Checkpoints
+// in, one per index and chain
+const SQLiteMetadataSchema = `CREATE TABLE IF NOT EXISTS metadata (
+	index_id  TEXT NOT NULL,
+	chain_id  TEXT NOT NULL,
+	chunk_id  INTEGER NOT NULL,
+	timestamp TEXT NOT NULL,
+	updated   TEXT NOT NULL,
+	PRIMARY KEY (index_id, chain_id)
+)`
+
+// SQLiteStore - persists Checkpoints to the metadata table of a SQLite
+// database, as alongside the artifacts of the 'sqlite' output, which
+// saves each chunk's Checkpoint by SaveSQLiteTx as it commits the chunk,
+// so the database records the last chunk applied to it
+type SQLiteStore struct {
+	path   string
+	logger *log.Logger
+}
+
+func NewSQLiteStore(l *log.Logger, path string) SQLiteStore {
+	return SQLiteStore{
+		path:   path,
+		logger: l,
+	}
+}
+
+// Load -
+func (ss SQLiteStore) Load(indexID, chainID string) (Checkpoint, bool, error) {
+	db, err := ss.open()
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	defer db.Close()
+
+	c := Checkpoint{IndexID: indexID, ChainID: chainID}
+	var timestamp, updated string
+	err = db.QueryRow(`SELECT chunk_id, timestamp, updated FROM metadata WHERE index_id = ? AND chain_id = ?`,
+		indexID, chainID).Scan(&c.ChunkID, &timestamp, &updated)
+	if err == sql.ErrNoRows {
+		return Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return Checkpoint{}, false, errors.Wrapf(err, "SQLiteStore: failed to read checkpoint from %s with cause", ss.path)
+	}
+
+	if c.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp); err != nil {
+		return Checkpoint{}, false, errors.Wrapf(err, "SQLiteStore: failed to parse checkpoint timestamp in %s with cause", ss.path)
+	}
+	if c.Updated, err = time.Parse(time.RFC3339Nano, updated); err != nil {
+		return Checkpoint{}, false, errors.Wrapf(err, "SQLiteStore: failed to parse checkpoint update time in %s with cause", ss.path)
+	}
+
+	return c, true, nil
+}
+
+// Save -
+func (ss SQLiteStore) Save(c Checkpoint) error {
+	db, err := ss.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := saveSQLite(db, c); err != nil {
+		return errors.Wrapf(err, "SQLiteStore: failed to write checkpoint to %s with cause", ss.path)
+	}
+
+	ss.logger.Printf("SQLiteStore: saved checkpoint for index %s chain %s at chunk %d (%s)",
+		c.IndexID, c.ChainID, c.ChunkID, c.Timestamp)
+	return nil
+}
+
+// SaveSQLiteTx - record the checkpoint in the transaction, as along with the
+// records of the chunk it checkpoints, so both are committed or neither is.
+// The metadata table must exist
+func SaveSQLiteTx(tx *sql.Tx, c Checkpoint) error {
+	return saveSQLite(tx, c)
+}
+
+// sqliteExecer - a *sql.DB or *sql.Tx
+type sqliteExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// saveSQLite - upsert the checkpoint's metadata row
+func saveSQLite(db sqliteExecer, c Checkpoint) error {
+	if c.Updated.IsZero() {
+		c.Updated = time.Now().UTC()
+	}
+
+	_, err := db.Exec(`INSERT INTO metadata (index_id, chain_id, chunk_id, timestamp, updated) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (index_id, chain_id) DO UPDATE SET
+			chunk_id = excluded.chunk_id, timestamp = excluded.timestamp, updated = excluded.updated`,
+		c.IndexID, c.ChainID, c.ChunkID, c.Timestamp.UTC().Format(time.RFC3339Nano), c.Updated.UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// open - the database, with its metadata table created if absent
+func (ss SQLiteStore) open() (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(ss.path), 0o755); err != nil {
+		return nil, errors.Wrapf(err, "SQLiteStore: failed to create directory for %s with cause", ss.path)
+	}
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=10000&_journal_mode=WAL", ss.path))
+	if err != nil {
+		return nil, errors.Wrapf(err, "SQLiteStore: failed to open %s with cause", ss.path)
+	}
+
+	if _, err := db.Exec(SQLiteMetadataSchema); err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "SQLiteStore: failed to create metadata table in %s with cause", ss.path)
+	}
+
+	return db, nil
+}
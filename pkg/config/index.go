@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -18,11 +20,14 @@ func Validate(logger *log.Logger, cfg Index) error {
 	if len(cfg.Meta.File) == 0 {
 		return errors.Errorf("Invalid configuration: index base file name (Meta.File) is required")
 	}
-	if len(cfg.Meta.ID) == 0 {
-		return errors.Errorf("Invalid configuration: index identifier (Meta.ID) is required")
-	}
-	if len(cfg.Meta.ChainID) == 0 {
-		return errors.Errorf("Invalid configuration: index chain ID (Meta.ChainID) is required")
+	// otherwise, both are discovered from the index properties
+	if len(cfg.Meta.Store) == 0 {
+		if len(cfg.Meta.ID) == 0 {
+			return errors.Errorf("Invalid configuration: index identifier (Meta.ID) is required unless Meta.Store is set")
+		}
+		if len(cfg.Meta.ChainID) == 0 {
+			return errors.Errorf("Invalid configuration: index chain ID (Meta.ChainID) is required unless Meta.Store is set")
+		}
 	}
 
 	if len(cfg.Source.Base) == 0 {
@@ -32,8 +37,34 @@ func Validate(logger *log.Logger, cfg Index) error {
 		return errors.Errorf("Invalid configuration: index location (Source.Type) is required")
 	}
 
-	if cfg.Output.Format != Log && cfg.Output.Format != CSV && cfg.Output.Format != JSON {
-		return errors.Errorf("Invalid configuration: valid format type (Output.Format) is required")
+	if cfg.Source.Legacy && cfg.Mode.Type != All {
+		return errors.New("Invalid configuration: legacy (Source.Legacy) indices only support Mode.Type 'all'")
+	}
+
+	// registered Sinks are resolved, and rejected if unknown, by the output package
+	if len(cfg.Output.FormatName()) == 0 {
+		return errors.Errorf("Invalid configuration: valid format type (Output.Format) or sink name (Output.Sink) is required")
+	}
+	if cfg.Output.Format == CSV {
+		switch cfg.Output.CSV.Delimiter {
+		case '"', '\r', '\n', utf8.RuneError:
+			return errors.Errorf("Invalid configuration: invalid CSV delimiter (Output.CSV.Delimiter) %q", cfg.Output.CSV.Delimiter)
+		}
+		if cfg.Output.CSV.Columns == PerTypeColumns && len(cfg.Output.File) == 0 {
+			return errors.New("Invalid configuration: per-type CSV columns (Output.CSV.Columns) require an output file (Output.File)")
+		}
+	}
+	if cfg.Output.Format == SQLite && len(cfg.Output.File) == 0 {
+		return errors.New("Invalid configuration: SQLite output requires a database file (Output.File)")
+	}
+	if cfg.Output.Format == Parquet {
+		// the footer is written last, so files are only readable once closed
+		if len(cfg.Output.File) == 0 {
+			return errors.New("Invalid configuration: Parquet output requires an output file (Output.File)")
+		}
+		if cfg.Output.Parquet.RowGroupSize < 0 || cfg.Output.Parquet.RowsPerFile < 0 {
+			return errors.New("Invalid configuration: Parquet row group size and rows per file (Output.Parquet) can't be negative")
+		}
 	}
 
 	if cfg.Mode.Type > All && len(cfg.Mode.After) == 0 && len(cfg.Mode.Only) == 0 {
@@ -73,6 +104,7 @@ type Index struct {
 	Verbose bool
 	Meta    Meta
 	Source  Source
+	Verify  Verify
 	Mode    Mode
 	Output  Output
 }
@@ -82,10 +114,29 @@ func (cfg Index) ResolveTarget(targetOrPattern string, targetArgs ...interface{}
 	return cfg.Source.Base + cfg.Meta.File + fmt.Sprintf(targetOrPattern, targetArgs...)
 }
 
+// ChunkID - the chunk ID of an incremental chunk target resolved as
+// ResolveTarget(".%d.gz", chunkID), or false for any other target
+func (cfg Index) ChunkID(target string) (int, bool) {
+	prefix := cfg.ResolveTarget(".")
+	if !strings.HasPrefix(target, prefix) || !strings.HasSuffix(target, ".gz") {
+		return 0, false
+	}
+
+	chunkID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(target, prefix), ".gz"))
+	if err != nil || chunkID < 0 {
+		return 0, false
+	}
+	return chunkID, true
+}
+
 type Meta struct {
 	ID      string // expected index ID, as in "nexus.index.id"
 	ChainID string // expected chain ID, as in "nexus.index.chain-id"
 	File    string // expected base name of source index resources like "nexus-maven-repository-index"
+	// if set, path to a JSON file where the index ID and chain ID are
+	// persisted. Either may then be left unset, to be discovered from the
+	// index properties on first contact, and expected on later runs
+	Store string
 }
 
 type Mode struct {
@@ -96,6 +147,9 @@ type Mode struct {
 	After string
 	// integer-valued string specifying the single chunk to process
 	Only string
+	// in 'after-chunk' mode, read the full index rather than fail
+	// if the incremental chain following Mode.After is broken
+	Fallback bool
 }
 
 func (m Mode) Incremental() bool {
@@ -120,8 +174,19 @@ var ModeTypes = map[string]ModeType{
 }
 
 type Source struct {
-	Base string     // either the base URL or absolute base path depending on SourceType
-	Type SourceType // enum of local filesystem or HTTP based index source types
+	Base   string     // either the base URL or absolute base path depending on SourceType
+	Type   SourceType // enum of local filesystem or HTTP based index source types
+	Legacy bool       // read the legacy Lucene-based ".zip" index rather than ".gz" chunks
+	Cache  string     // if set, HTTP resources are cached under this local directory
+}
+
+// Verify - optional provenance checks on each index file read
+type Verify struct {
+	// compare the content against the published ".sha1" and ".md5" siblings
+	Checksums bool
+	// if set, path to an OpenPGP keyring file (armored or binary) used to
+	// verify the published detached ".asc" signature siblings
+	Keyring string
 }
 
 type SourceType uint8
@@ -133,8 +198,66 @@ const (
 )
 
 type Output struct {
-	Format OutputType
-	File   string // defaults to os.Stdout if undefined
+	Format  OutputType
+	Sink    string         // if set, the name of a Sink registered with the output package, instead of Format
+	File    string         // defaults to os.Stdout if undefined. the database, for 'sqlite'
+	CSV     CSVOptions     // settings for the 'csv' OutputType
+	Parquet ParquetOptions // settings for the 'parquet' OutputType
+}
+
+// CSVOptions - column selection and value encoding for the 'csv' OutputType
+type CSVOptions struct {
+	// how the columns are chosen, when Fields is unset
+	Columns CSVColumns
+	// if set, exactly these columns, in order. "record_type" names the
+	// column holding the record type; any other is a Record key
+	Fields []string
+	// field delimiter, defaults to ','
+	Delimiter rune
+	// joins list values like class names and groups, defaults to "|"
+	ListSeparator string
+	// Go time layout for timestamps, defaults to time.RFC3339Nano
+	TimeFormat string
+}
+
+// FormatName - the name the output Sink is registered under
+func (o Output) FormatName() string {
+	if len(o.Sink) > 0 {
+		return o.Sink
+	}
+	for name, format := range OutputFormats {
+		if format == o.Format {
+			return name
+		}
+	}
+	return ""
+}
+
+// ParquetOptions - row group sizing and file rolling for the 'parquet' OutputType
+type ParquetOptions struct {
+	// target uncompressed bytes buffered per row group, defaults to 128 MiB
+	RowGroupSize int64
+	// if set, rolls over to a new file after this many rows. Files are
+	// then named like Output.File, suffixed with a part number
+	RowsPerFile int64
+	// if set, each flush also rolls over to a new file, completing the
+	// last, so that flushed rows are readable, as in a long-running watch
+	RollOnFlush bool
+}
+
+type CSVColumns uint8
+
+const (
+	// one file with the union of the columns of every record type
+	UnionColumns CSVColumns = iota
+	// one file per record type, each with only its own columns,
+	// named like Output.File, suffixed with the record type
+	PerTypeColumns
+)
+
+var CSVColumnModes = map[string]CSVColumns{
+	"union":    UnionColumns,
+	"per-type": PerTypeColumns,
 }
 
 type OutputType uint8
@@ -144,10 +267,16 @@ const (
 	Log
 	CSV
 	JSON
+	NDJSON
+	Parquet
+	SQLite
 )
 
 var OutputFormats = map[string]OutputType{
-	"log":  Log,
-	"csv":  CSV,
-	"json": JSON,
+	"log":     Log,
+	"csv":     CSV,
+	"json":    JSON,
+	"ndjson":  NDJSON,
+	"parquet": Parquet,
+	"sqlite":  SQLite,
 }
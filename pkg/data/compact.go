@@ -0,0 +1,174 @@
+package data
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Raw value of the "DESCRIPTOR" key on "DESCRIPTOR" type Records
+	DescriptorValue = "NexusIndex"
+
+	// Index format version stored on "DESCRIPTOR" type Records
+	DescriptorVersion = "1.0"
+)
+
+// CompactRecord - the inverse of NewRecord. Flattens a well-formed Record
+// into the raw index keys and values stored in an index chunk.
+// https://github.com/apache/maven-indexer/blob/31052fdeebc8a9f845eb18cd4c13669b316b3e29/indexer-reader/src/main/java/org/apache/maven/index/reader/RecordCompactor.java
+func CompactRecord(record Record) (map[string]string, error) {
+	switch record.Type() {
+	case Descriptor:
+		return compactDescriptor(record), nil
+	case AllGroups:
+		return compactGroups(record, keys.AllGroups, keys.AllGroupsList), nil
+	case RootGroups:
+		return compactGroups(record, keys.RootGroups, keys.RootGroupsList), nil
+	case ArtifactRemove:
+		return compactArtifactRemove(record), nil
+	case ArtifactAdd:
+		return compactArtifactAdd(record), nil
+	}
+
+	return nil, errors.Errorf("CompactRecord: unknown RecordType %d", record.Type())
+}
+
+func compactDescriptor(record Record) map[string]string {
+	version := DescriptorVersion
+	if v, ok := record.Get(keys.Version).(string); ok && len(v) > 0 {
+		version = v
+	}
+
+	return map[string]string{
+		keys.Descriptor: DescriptorValue,
+		IDXINFO:         version + RecordValueSeparator + valueToString(record.Get(keys.RepositoryID)),
+	}
+}
+
+func compactGroups(record Record, typeKey, listKey keys.Record) map[string]string {
+	return map[string]string{
+		typeKey: typeKey,
+		listKey: valueToString(record.Get(listKey)),
+	}
+}
+
+func compactArtifactRemove(record Record) map[string]string {
+	out := map[string]string{
+		keys.Del: compactUInfo(record),
+	}
+	putIfNotNull(out, RecordModifiedKey, record.Get(keys.RecordModified))
+
+	return out
+}
+
+func compactArtifactAdd(record Record) map[string]string {
+	out := map[string]string{
+		UInfoKey: compactUInfo(record),
+		InfoKey:  compactInfo(record),
+	}
+
+	putIfNotNull(out, RecordModifiedKey, record.Get(keys.RecordModified))
+	putIfNotNull(out, NameKey, record.Get(keys.Name))
+	putIfNotNull(out, DescriptionKey, record.Get(keys.Description))
+	putIfNotNull(out, SHA1Key, record.Get(keys.SHA1))
+	putIfNotNull(out, ClassnamesKey, record.Get(keys.Classnames))
+
+	// Maven Plugin fields, if present
+	putIfNotNull(out, "px", record.Get(keys.PluginPrefix))
+	putIfNotNull(out, "gx", record.Get(keys.PluginGoals))
+
+	// OSGI fields, if present. these raw keys match the parsed keys
+	for _, key := range OSGIRecordKeys {
+		putIfNotNull(out, string(key), record.Get(key))
+	}
+
+	return out
+}
+
+func compactUInfo(record Record) string {
+	classifier := record.Get(keys.Classifier)
+
+	vals := []string{
+		valueToString(record.Get(keys.GroupID)),
+		valueToString(record.Get(keys.ArtifactID)),
+		valueToString(record.Get(keys.Version)),
+		orNotAvailable(classifier),
+	}
+	if classifier != nil {
+		vals = append(vals, valueToString(record.Get(keys.FileExtension)))
+	}
+
+	return strings.Join(vals, RecordValueSeparator)
+}
+
+func compactInfo(record Record) string {
+	fileModified := "0"
+	if fm := record.Get(keys.FileModified); fm != nil {
+		fileModified = valueToString(fm)
+	}
+	fileSize := "-1"
+	if fs := record.Get(keys.FileSize); fs != nil {
+		fileSize = valueToString(fs)
+	}
+
+	return strings.Join([]string{
+		orNotAvailable(record.Get(keys.Packaging)),
+		fileModified,
+		fileSize,
+		flagToString(record.Get(keys.HasSources)),
+		flagToString(record.Get(keys.HasJavadoc)),
+		flagToString(record.Get(keys.HasSignature)),
+		orNotAvailable(record.Get(keys.FileExtension)),
+	}, RecordValueSeparator)
+}
+
+func putIfNotNull(out map[string]string, rawKey string, value interface{}) {
+	if value == nil {
+		return
+	}
+
+	if str := valueToString(value); len(str) > 0 {
+		out[rawKey] = str
+	}
+}
+
+func orNotAvailable(value interface{}) string {
+	if str := valueToString(value); len(str) > 0 {
+		return str
+	}
+
+	return NotAvailable
+}
+
+func flagToString(value interface{}) string {
+	if b, ok := value.(bool); ok && b {
+		return "1"
+	}
+
+	return "0"
+}
+
+// render a parsed Record value in the raw form stored in the index
+func valueToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, RecordValueSeparator)
+	case time.Time:
+		return strconv.FormatInt(v.UnixMilli(), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return flagToString(v)
+	}
+
+	return ""
+}
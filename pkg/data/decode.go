@@ -0,0 +1,206 @@
+package data
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/pkg/errors"
+)
+
+// DecodeTag - the struct tag mapping a keys.Record name onto a struct field, as in:
+//
+//	type Artifact struct {
+//		Kind     string    `maven:"recordType"`
+//		GroupID  string    `maven:"groupId"`
+//		Size     int64     `maven:"fileSize"`
+//		Modified time.Time `maven:"fileModified"`
+//		Classes  []string  `maven:"classNames"`
+//		Sources  *bool     `maven:"hasSources"`
+//	}
+//
+// Untagged fields, and fields tagged "-", are left untouched. The tag name
+// "recordType" resolves the Record's RecordTypeNames entry
+const DecodeTag = "maven"
+
+// RecordTypeTag - the pseudo-key resolving a Record's type name when decoding
+const RecordTypeTag = "recordType"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Decode - populate a new T, which must be a struct, from the Record
+// values named by its fields' DecodeTag tags. See Record#Unmarshal
+func Decode[T any](rec Record) (T, error) {
+	var out T
+	err := rec.Unmarshal(&out)
+	return out, err
+}
+
+// Unmarshal - populate the struct pointed to by v from the Record values named
+// by its fields' DecodeTag tags. Values absent from the Record leave fields
+// untouched. Values convert to string, bool, integer, time.Time and []string
+// fields, or pointers to these, else an error is returned
+func (r Record) Unmarshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("Unmarshal: expected non-nil pointer to struct, got: %T", v)
+	}
+
+	return r.unmarshalStruct(rv.Elem())
+}
+
+func (r Record) unmarshalStruct(sv reflect.Value) error {
+	st := sv.Type()
+	for ndx := 0; ndx < st.NumField(); ndx++ {
+		field := st.Field(ndx)
+		fv := sv.Field(ndx)
+
+		tag, tagged := field.Tag.Lookup(DecodeTag)
+		if !tagged {
+			// recurse into untagged embedded structs
+			if field.Anonymous && fv.Kind() == reflect.Struct {
+				if err := r.unmarshalStruct(fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || len(name) == 0 {
+			continue
+		}
+		if !field.IsExported() {
+			return errors.Errorf("Unmarshal: field %s.%s tagged %q is not exported", st.Name(), field.Name, name)
+		}
+
+		var value interface{}
+		if name == RecordTypeTag {
+			value = RecordTypeNames[r.kind]
+		} else {
+			value = r.Get(keys.Record(name))
+		}
+		if value == nil {
+			continue
+		}
+
+		if err := assignValue(fv, value); err != nil {
+			return errors.Wrapf(err, "Unmarshal: failed to assign %q to field %s.%s with cause", name, st.Name(), field.Name)
+		}
+	}
+
+	return nil
+}
+
+// convert a parsed Record value to the field's type, and assign it
+func assignValue(fv reflect.Value, value interface{}) error {
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := assignValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+
+	if fv.Type() == timeType {
+		t, err := toTime(value)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(valueToString(value))
+		return nil
+
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			fv.SetBool(v)
+			return nil
+		case string:
+			fv.SetBool(v == "1" || strings.EqualFold(v, "true"))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(i) {
+			return errors.Errorf("value %d overflows %s", i, fv.Type())
+		}
+		fv.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := toInt64(value)
+		if err != nil {
+			return err
+		}
+		if i < 0 || fv.OverflowUint(uint64(i)) {
+			return errors.Errorf("value %d overflows %s", i, fv.Type())
+		}
+		fv.SetUint(uint64(i))
+		return nil
+
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.String {
+			var list []string
+			switch v := value.(type) {
+			case []string:
+				list = v
+			case string:
+				list = splitValue(v)
+			default:
+				return errors.Errorf("cannot convert %T to %s", value, fv.Type())
+			}
+
+			out := reflect.MakeSlice(fv.Type(), len(list), len(list))
+			for ndx, elem := range list {
+				out.Index(ndx).SetString(elem)
+			}
+			fv.Set(out)
+			return nil
+		}
+	}
+
+	return errors.Errorf("cannot convert %T to %s", value, fv.Type())
+}
+
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.UnixMilli(v).UTC(), nil
+	case string:
+		if millis, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.UnixMilli(millis).UTC(), nil
+		}
+		return time.Parse(time.RFC3339, v)
+	}
+
+	return time.Time{}, errors.Errorf("cannot convert %T to time.Time", value)
+}
+
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case time.Time:
+		return v.UnixMilli(), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+
+	return 0, errors.Errorf("cannot convert %T to an integer", value)
+}
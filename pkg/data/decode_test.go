@@ -0,0 +1,75 @@
+package data
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type coordinates struct {
+	GroupID    string `maven:"groupId"`
+	ArtifactID string `maven:"artifactId"`
+	Version    string `maven:"version"`
+}
+
+type artifact struct {
+	coordinates
+
+	Kind       string    `maven:"recordType"`
+	Size       int64     `maven:"fileSize"`
+	SmallSize  int32     `maven:"fileSize"`
+	Modified   time.Time `maven:"fileModified"`
+	Classes    []string  `maven:"classNames"`
+	Goals      []string  `maven:"pluginGoals"`
+	Sources    *bool     `maven:"hasSources"`
+	Classifier *string   `maven:"classifier"`
+	Ignored    string    `maven:"-"`
+	Untagged   string
+}
+
+func TestDecode(t *testing.T) {
+	record, err := NewRecord(log.Default(), map[string]string{
+		UInfoKey:      "org.example|widget|1.0|NA|jar",
+		InfoKey:       "jar|1243533415343|1024|1|0|1|jar",
+		ClassnamesKey: "/org/example/Widget|/org/example/Gadget",
+		"gx":          "compile|test",
+	})
+	require.NoError(t, err)
+
+	got, err := Decode[artifact](record)
+	require.NoError(t, err)
+
+	sources := true
+	require.Equal(t, artifact{
+		coordinates: coordinates{
+			GroupID:    "org.example",
+			ArtifactID: "widget",
+			Version:    "1.0",
+		},
+		Kind:      "artifact_add",
+		Size:      1024,
+		SmallSize: 1024,
+		Modified:  time.UnixMilli(1243533415343).UTC(),
+		Classes:   []string{"/org/example/Widget", "/org/example/Gadget"},
+		Goals:     []string{"compile", "test"},
+		Sources:   &sources,
+	}, got)
+
+	var coords coordinates
+	require.NoError(t, record.Unmarshal(&coords))
+	require.Equal(t, "widget", coords.ArtifactID)
+
+	require.Error(t, record.Unmarshal(coords))
+
+	var bad struct {
+		Modified bool `maven:"classNames"`
+	}
+	require.Error(t, record.Unmarshal(&bad))
+
+	var overflow struct {
+		Size int8 `maven:"fileSize"`
+	}
+	require.Error(t, record.Unmarshal(&overflow))
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"log"
+	"sort"
+)
+
+// FieldFlags - the byte of index bit flags preceding each raw field in an index chunk
+// https://github.com/apache/maven-indexer/blob/31052fdeebc8a9f845eb18cd4c13669b316b3e29/indexer-reader/src/main/java/org/apache/maven/index/reader/Chunk.java
+type FieldFlags uint8
+
+const (
+	FlagIndexed    FieldFlags = 0x01
+	FlagTokenized  FieldFlags = 0x02
+	FlagStored     FieldFlags = 0x04
+	FlagCompressed FieldFlags = 0x08
+)
+
+func (f FieldFlags) Indexed() bool {
+	return f&FlagIndexed != 0
+}
+
+func (f FieldFlags) Tokenized() bool {
+	return f&FlagTokenized != 0
+}
+
+func (f FieldFlags) Stored() bool {
+	return f&FlagStored != 0
+}
+
+func (f FieldFlags) Compressed() bool {
+	return f&FlagCompressed != 0
+}
+
+// DefaultFieldFlags - the flags the Java ChunkWriter assigns to each raw key
+func DefaultFieldFlags(rawKey string) FieldFlags {
+	indexed := !(rawKey == InfoKey || rawKey == RecordModifiedKey)
+	tokenized := !(rawKey == InfoKey || rawKey == RecordModifiedKey || rawKey == SHA1Key || rawKey == "px")
+
+	flags := FlagStored
+	if indexed {
+		flags |= FlagIndexed
+	}
+	if tokenized {
+		flags |= FlagTokenized
+	}
+
+	return flags
+}
+
+// RawField - a key, value and flags triple as stored in an index chunk
+type RawField struct {
+	Key   string
+	Value string
+	Flags FieldFlags
+}
+
+// NewRecordFromFields - as NewRecord, but retains the raw fields, in
+// order and including any the parser does not recognize, on the Record
+func NewRecordFromFields(logger *log.Logger, fields []RawField) (Record, error) {
+	indexRecord := make(map[string]string, len(fields))
+	for _, field := range fields {
+		indexRecord[field.Key] = field.Value
+	}
+
+	out, err := newRecord(indexRecord)
+	out.raw = fields
+	return out, err
+}
+
+// resolve raw fields from a raw index record, in key order, with default flags
+func rawFields(indexRecord map[string]string) []RawField {
+	out := make([]RawField, 0, len(indexRecord))
+	for key, value := range indexRecord {
+		out = append(out, RawField{Key: key, Value: value, Flags: DefaultFieldFlags(key)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+
+	return out
+}
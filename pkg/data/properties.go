@@ -1,7 +1,10 @@
 package data
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/elireisman/maven-index-reader-go/internal/utils"
@@ -9,6 +12,27 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Well-known keys of the index properties file
+const (
+	// Index ID, like "central"
+	PropertyID = "nexus.index.id"
+
+	// ID of the incremental chunk chain; changes when the index is rebuilt
+	PropertyChainID = "nexus.index.chain-id"
+
+	// Timestamp of the most recent index publication
+	PropertyTimestamp = "nexus.index.timestamp"
+
+	// Legacy alias of PropertyTimestamp
+	PropertyTime = "nexus.index.time"
+
+	// Chunk ID of the most recently published incremental chunk
+	PropertyLastIncremental = "nexus.index.last-incremental"
+
+	// Prefix of the "nexus.index.incremental-N" keys listing published chunk IDs
+	PropertyIncrementalPrefix = "nexus.index.incremental-"
+)
+
 // Properties represents a well-formed Java properties file
 // along with some convenience methods.
 type Properties struct {
@@ -16,9 +40,89 @@ type Properties struct {
 }
 
 func NewProperties(props map[string]string) Properties {
+	if props == nil {
+		props = map[string]string{}
+	}
 	return Properties{props}
 }
 
+// Keys - obtain the sorted list of keys present in the Properties
+func (pr Properties) Keys() []string {
+	out := make([]string, 0, len(pr.properties))
+	for key := range pr.properties {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+// Set - add or overwrite the raw value stored under key
+func (pr Properties) Set(key, value string) {
+	pr.properties[key] = value
+}
+
+// SetInt - add or overwrite the integer value stored under key
+func (pr Properties) SetInt(key string, value int) {
+	pr.properties[key] = strconv.Itoa(value)
+}
+
+// SetTimestamp - add or overwrite the timestamp value stored under
+// key, in the format expected by GetAsTimestamp
+func (pr Properties) SetTimestamp(key string, value time.Time) {
+	pr.properties[key] = utils.FormatTimestamp(value)
+}
+
+// Delete - remove key and its value, if present
+func (pr Properties) Delete(key string) {
+	delete(pr.properties, key)
+}
+
+// IncrementalKey - resolve the "nexus.index.incremental-N" key for n
+func IncrementalKey(n int) string {
+	return fmt.Sprintf("%s%d", PropertyIncrementalPrefix, n)
+}
+
+// Incrementals - the chunk IDs listed by the "nexus.index.incremental-N"
+// entries, in ascending order. The list is empty if there are none, as for
+// an index that has only ever published its full chunk
+func (pr Properties) Incrementals() ([]int, error) {
+	var out []int
+	seen := map[int]bool{}
+	for key, val := range pr.properties {
+		suffix := strings.TrimPrefix(key, PropertyIncrementalPrefix)
+		if suffix == key {
+			continue
+		}
+		if _, err := strconv.Atoi(suffix); err != nil {
+			continue
+		}
+
+		chunkID, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Incrementals: failed to parse expected chunk ID %q for key %q with cause", val, key)
+		}
+		if !seen[chunkID] {
+			seen[chunkID] = true
+			out = append(out, chunkID)
+		}
+	}
+	sort.Ints(out)
+
+	return out, nil
+}
+
+// LastIncremental - the "nexus.index.last-incremental" chunk ID, or false if
+// absent, as for an index published before its first incremental chunk
+func (pr Properties) LastIncremental() (int, bool, error) {
+	if _, found := pr.properties[PropertyLastIncremental]; !found {
+		return 0, false, nil
+	}
+
+	lastIncr, err := pr.GetAsInt(PropertyLastIncremental)
+	return lastIncr, err == nil, err
+}
+
 func (pr Properties) GetAsString(key string) (string, error) {
 	val, found := pr.properties[key]
 	if !found {
@@ -34,9 +138,9 @@ func (pr Properties) GetAsInt(key string) (int, error) {
 		return 0, errors.Errorf("GetAsInt: expected properties key %q not found", key)
 	}
 
-	out, err := strconv.Atoi(val)
+	out, err := strconv.Atoi(strings.TrimSpace(val))
 	if err != nil {
-		return 0, errors.Wrapf(err, "GetAsInt: failed to parse expected integer value %q for key %q with cause", key, val)
+		return 0, errors.Wrapf(err, "GetAsInt: failed to parse expected integer value %q for key %q with cause", val, key)
 	}
 
 	return out, nil
@@ -48,9 +152,9 @@ func (pr Properties) GetAsTimestamp(key string) (time.Time, error) {
 		return time.Now().UTC(), errors.Errorf("GetAsTimestamp: expected properties key %q not found", key)
 	}
 
-	t, err := utils.GetTimestamp(val)
+	t, err := utils.GetTimestamp(strings.TrimSpace(val))
 	if err != nil {
-		return time.Now().UTC(), errors.Wrapf(err, "GetAsTimestamp: failed to parse expected time value %q for key %q with cause", key, val)
+		return time.Now().UTC(), errors.Wrapf(err, "GetAsTimestamp: failed to parse expected time value %q for key %q with cause", val, key)
 	}
 
 	return t, nil
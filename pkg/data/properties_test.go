@@ -0,0 +1,38 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPropertiesIncrementals(t *testing.T) {
+	props := NewProperties(map[string]string{
+		PropertyID:                  "central",
+		PropertyLastIncremental:     "771",
+		IncrementalKey(0):           "771",
+		IncrementalKey(1):           " 770",
+		IncrementalKey(2):           "769",
+		"nexus.index.incremental-x": "not-a-chunk",
+	})
+
+	incrementals, err := props.Incrementals()
+	require.NoError(t, err)
+	require.Equal(t, []int{769, 770, 771}, incrementals)
+
+	lastIncr, ok, err := props.LastIncremental()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 771, lastIncr)
+
+	incrementals, err = NewProperties(nil).Incrementals()
+	require.NoError(t, err)
+	require.Empty(t, incrementals)
+	_, ok, err = NewProperties(nil).LastIncremental()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	props.Set(IncrementalKey(3), "seven")
+	_, err = props.Incrementals()
+	require.Error(t, err)
+}
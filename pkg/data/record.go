@@ -116,6 +116,7 @@ type Record struct {
 	kind RecordType
 	data map[keys.Record]interface{}
 	keys []keys.Record
+	raw  []RawField
 }
 
 // Type - expose index record types for callers
@@ -148,9 +149,46 @@ func (r Record) Payload() map[keys.Record]interface{} {
 	return r.data
 }
 
+// Raw - obtain the raw key, value and flags triples the Record was
+// parsed from, including any keys the parser does not recognize
+func (r Record) Raw() []RawField {
+	return r.raw
+}
+
+// RawValue - obtain the raw value stored under the raw index key, if present
+func (r Record) RawValue(rawKey string) (string, bool) {
+	for _, field := range r.raw {
+		if field.Key == rawKey {
+			return field.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Flags - obtain the index bit flags stored with the raw index key, if present
+func (r Record) Flags(rawKey string) (FieldFlags, bool) {
+	for _, field := range r.raw {
+		if field.Key == rawKey {
+			return field.Flags, true
+		}
+	}
+
+	return 0, false
+}
+
 // NewRecord - parses the input map then populates
 // and returns a well-formed Record, or an error.
+// The raw fields are retained with default flags
 func NewRecord(logger *log.Logger, indexRecord map[string]string) (Record, error) {
+	raw := rawFields(indexRecord)
+
+	out, err := newRecord(indexRecord)
+	out.raw = raw
+	return out, err
+}
+
+func newRecord(indexRecord map[string]string) (Record, error) {
 	if _, found := indexRecord[keys.Descriptor]; found {
 		return newDescriptorRecord(indexRecord)
 	}
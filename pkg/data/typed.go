@@ -0,0 +1,302 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/pkg/errors"
+)
+
+// OSGIRecordKeys - the optional OSGi manifest attributes of "ARTIFACT_ADD" Records
+var OSGIRecordKeys = []keys.Record{
+	keys.OSGIBundleSymbolicName,
+	keys.OSGIBundleVersion,
+	keys.OSGIExportPackage,
+	keys.OSGIExportService,
+	keys.OSGIBundleDescription,
+	keys.OSGIBundleName,
+	keys.OSGIBundleLicense,
+	keys.OSGIBundleDocURL,
+	keys.OSGIImportPackage,
+	keys.OSGIRequireBundle,
+	keys.OSGIProvideCapability,
+	keys.OSGIRequireCapability,
+	keys.OSGIFragmentHost,
+	keys.OSGIBREE,
+	keys.OSGISHA256,
+}
+
+// ArtifactAddRecord - typed view of an "ARTIFACT_ADD" Record
+type ArtifactAddRecord struct {
+	RecordModified time.Time
+	GroupID        string
+	ArtifactID     string
+	Version        string
+	Classifier     string
+	FileExtension  string
+	FileModified   time.Time
+	FileSize       int64
+	Packaging      string
+	HasSources     bool
+	HasJavadoc     bool
+	HasSignature   bool
+	Name           string
+	Description    string
+	SHA1           string
+	Classnames     []string
+	PluginPrefix   string
+	PluginGoals    []string
+
+	// OSGi manifest attributes, if present, keyed by the OSGIRecordKeys
+	OSGI map[keys.Record]string
+}
+
+// ArtifactRemoveRecord - typed view of an "ARTIFACT_REMOVE" Record
+type ArtifactRemoveRecord struct {
+	RecordModified time.Time
+	GroupID        string
+	ArtifactID     string
+	Version        string
+	Classifier     string
+	FileExtension  string
+	Packaging      string
+}
+
+// DescriptorRecord - typed view of a "DESCRIPTOR" Record
+type DescriptorRecord struct {
+	RepositoryID string
+	Version      string
+}
+
+// GroupsRecord - typed view of an "all groups" or "root groups" Record
+type GroupsRecord struct {
+	// one of AllGroups or RootGroups
+	Kind   RecordType
+	Groups []string
+}
+
+// AsArtifactAdd - obtain the typed view of an "ARTIFACT_ADD" Record
+func (r Record) AsArtifactAdd() (ArtifactAddRecord, error) {
+	if r.kind != ArtifactAdd {
+		return ArtifactAddRecord{}, errors.Errorf("AsArtifactAdd: expected record of type %s, got: %s", RecordTypeNames[ArtifactAdd], RecordTypeNames[r.kind])
+	}
+
+	out := ArtifactAddRecord{
+		RecordModified: r.getTime(keys.RecordModified),
+		GroupID:        r.getString(keys.GroupID),
+		ArtifactID:     r.getString(keys.ArtifactID),
+		Version:        r.getString(keys.Version),
+		Classifier:     r.getString(keys.Classifier),
+		FileExtension:  r.getString(keys.FileExtension),
+		FileModified:   r.getTime(keys.FileModified),
+		FileSize:       r.getInt64(keys.FileSize),
+		Packaging:      r.getString(keys.Packaging),
+		HasSources:     r.getBool(keys.HasSources),
+		HasJavadoc:     r.getBool(keys.HasJavadoc),
+		HasSignature:   r.getBool(keys.HasSignature),
+		Name:           r.getString(keys.Name),
+		Description:    r.getString(keys.Description),
+		SHA1:           r.getString(keys.SHA1),
+		Classnames:     r.getStrings(keys.Classnames),
+		PluginPrefix:   r.getString(keys.PluginPrefix),
+		PluginGoals:    r.getStrings(keys.PluginGoals),
+	}
+	for _, key := range OSGIRecordKeys {
+		if v := r.getString(key); len(v) > 0 {
+			if out.OSGI == nil {
+				out.OSGI = map[keys.Record]string{}
+			}
+			out.OSGI[key] = v
+		}
+	}
+
+	return out, nil
+}
+
+// AsArtifactRemove - obtain the typed view of an "ARTIFACT_REMOVE" Record
+func (r Record) AsArtifactRemove() (ArtifactRemoveRecord, error) {
+	if r.kind != ArtifactRemove {
+		return ArtifactRemoveRecord{}, errors.Errorf("AsArtifactRemove: expected record of type %s, got: %s", RecordTypeNames[ArtifactRemove], RecordTypeNames[r.kind])
+	}
+
+	return ArtifactRemoveRecord{
+		RecordModified: r.getTime(keys.RecordModified),
+		GroupID:        r.getString(keys.GroupID),
+		ArtifactID:     r.getString(keys.ArtifactID),
+		Version:        r.getString(keys.Version),
+		Classifier:     r.getString(keys.Classifier),
+		FileExtension:  r.getString(keys.FileExtension),
+		Packaging:      r.getString(keys.Packaging),
+	}, nil
+}
+
+// AsDescriptor - obtain the typed view of a "DESCRIPTOR" Record
+func (r Record) AsDescriptor() (DescriptorRecord, error) {
+	if r.kind != Descriptor {
+		return DescriptorRecord{}, errors.Errorf("AsDescriptor: expected record of type %s, got: %s", RecordTypeNames[Descriptor], RecordTypeNames[r.kind])
+	}
+
+	return DescriptorRecord{
+		RepositoryID: r.getString(keys.RepositoryID),
+		Version:      r.getString(keys.Version),
+	}, nil
+}
+
+// AsGroups - obtain the typed view of an "all groups" or "root groups" Record
+func (r Record) AsGroups() (GroupsRecord, error) {
+	switch r.kind {
+	case AllGroups:
+		return GroupsRecord{Kind: AllGroups, Groups: r.getStrings(keys.AllGroupsList)}, nil
+	case RootGroups:
+		return GroupsRecord{Kind: RootGroups, Groups: r.getStrings(keys.RootGroupsList)}, nil
+	}
+
+	return GroupsRecord{}, errors.Errorf("AsGroups: expected record of type %s or %s, got: %s",
+		RecordTypeNames[AllGroups], RecordTypeNames[RootGroups], RecordTypeNames[r.kind])
+}
+
+// Record - convert the typed view into a Record. Zero-valued
+// optional attributes are omitted, as if absent in the index
+func (a ArtifactAddRecord) Record() Record {
+	out := Record{
+		kind: ArtifactAdd,
+		data: map[keys.Record]interface{}{},
+		keys: ArtifactAddRecordKeys,
+	}
+
+	out.putTime(keys.RecordModified, a.RecordModified)
+	out.putString(keys.GroupID, a.GroupID)
+	out.putString(keys.ArtifactID, a.ArtifactID)
+	out.putString(keys.Version, a.Version)
+	out.putString(keys.Classifier, a.Classifier)
+	out.putString(keys.FileExtension, a.FileExtension)
+	out.putTime(keys.FileModified, a.FileModified)
+	out.data[keys.FileSize] = a.FileSize
+	out.putString(keys.Packaging, a.Packaging)
+	out.data[keys.HasSources] = a.HasSources
+	out.data[keys.HasJavadoc] = a.HasJavadoc
+	out.data[keys.HasSignature] = a.HasSignature
+	out.putString(keys.Name, a.Name)
+	out.putString(keys.Description, a.Description)
+	out.putString(keys.SHA1, a.SHA1)
+	out.putStrings(keys.Classnames, a.Classnames)
+	out.putString(keys.PluginPrefix, a.PluginPrefix)
+	if len(a.PluginGoals) > 0 {
+		// the parser retains plugin goals in raw form
+		out.data[keys.PluginGoals] = strings.Join(a.PluginGoals, RecordValueSeparator)
+	}
+	for key, v := range a.OSGI {
+		out.putString(key, v)
+	}
+
+	return out
+}
+
+// Record - convert the typed view into a Record. Zero-valued
+// optional attributes are omitted, as if absent in the index
+func (a ArtifactRemoveRecord) Record() Record {
+	out := Record{
+		kind: ArtifactRemove,
+		data: map[keys.Record]interface{}{},
+		keys: ArtifactRemoveRecordKeys,
+	}
+
+	out.putTime(keys.RecordModified, a.RecordModified)
+	out.putString(keys.GroupID, a.GroupID)
+	out.putString(keys.ArtifactID, a.ArtifactID)
+	out.putString(keys.Version, a.Version)
+	out.putString(keys.Classifier, a.Classifier)
+	out.putString(keys.FileExtension, a.FileExtension)
+	out.putString(keys.Packaging, a.Packaging)
+
+	return out
+}
+
+// Record - convert the typed view into a Record
+func (d DescriptorRecord) Record() Record {
+	out := Record{
+		kind: Descriptor,
+		data: map[keys.Record]interface{}{},
+		keys: DescriptorRecordKeys,
+	}
+
+	out.putString(keys.RepositoryID, d.RepositoryID)
+	out.putString(keys.Version, d.Version)
+
+	return out
+}
+
+// Record - convert the typed view into a Record. Kind defaults to AllGroups
+func (g GroupsRecord) Record() Record {
+	if g.Kind == RootGroups {
+		out := Record{
+			kind: RootGroups,
+			data: map[keys.Record]interface{}{},
+			keys: RootGroupsRecordKeys,
+		}
+		out.putStrings(keys.RootGroupsList, g.Groups)
+		return out
+	}
+
+	out := Record{
+		kind: AllGroups,
+		data: map[keys.Record]interface{}{},
+		keys: AllGroupsRecordKeys,
+	}
+	out.putStrings(keys.AllGroupsList, g.Groups)
+	return out
+}
+
+func (r Record) getString(key keys.Record) string {
+	v, _ := r.data[key].(string)
+	return v
+}
+
+func (r Record) getTime(key keys.Record) time.Time {
+	v, _ := r.data[key].(time.Time)
+	return v
+}
+
+func (r Record) getInt64(key keys.Record) int64 {
+	v, _ := r.data[key].(int64)
+	return v
+}
+
+func (r Record) getBool(key keys.Record) bool {
+	v, _ := r.data[key].(bool)
+	return v
+}
+
+// list values may be parsed, or retained in raw form
+func (r Record) getStrings(key keys.Record) []string {
+	switch v := r.data[key].(type) {
+	case []string:
+		return v
+	case string:
+		if len(strings.TrimSpace(v)) > 0 {
+			return splitValue(v)
+		}
+	}
+
+	return nil
+}
+
+func (r Record) putString(key keys.Record, v string) {
+	if len(v) > 0 {
+		r.data[key] = v
+	}
+}
+
+func (r Record) putTime(key keys.Record, v time.Time) {
+	if !v.IsZero() {
+		r.data[key] = v.UTC()
+	}
+}
+
+func (r Record) putStrings(key keys.Record, v []string) {
+	if len(v) > 0 {
+		r.data[key] = v
+	}
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactAddRecord(t *testing.T) {
+	record, err := NewRecord(log.Default(), map[string]string{
+		UInfoKey:               "org.example|widget|1.0|sources|jar",
+		InfoKey:                "jar|1243533415343|1024|1|0|1|jar",
+		RecordModifiedKey:      "1243533417953",
+		NameKey:                "Widget",
+		SHA1Key:                "38bb5a445e9aa5a38581743ede58f46c0f1ce321",
+		ClassnamesKey:          "/org/example/Widget|/org/example/Gadget",
+		"gx":                   "compile|test",
+		keys.OSGIBundleVersion: "1.0.0",
+	})
+	require.NoError(t, err)
+
+	typed, err := record.AsArtifactAdd()
+	require.NoError(t, err)
+	require.Equal(t, ArtifactAddRecord{
+		RecordModified: time.UnixMilli(1243533417953).UTC(),
+		GroupID:        "org.example",
+		ArtifactID:     "widget",
+		Version:        "1.0",
+		Classifier:     "sources",
+		FileExtension:  "jar",
+		FileModified:   time.UnixMilli(1243533415343).UTC(),
+		FileSize:       1024,
+		Packaging:      "jar",
+		HasSources:     true,
+		HasJavadoc:     false,
+		HasSignature:   true,
+		Name:           "Widget",
+		SHA1:           "38bb5a445e9aa5a38581743ede58f46c0f1ce321",
+		Classnames:     []string{"/org/example/Widget", "/org/example/Gadget"},
+		PluginGoals:    []string{"compile", "test"},
+		OSGI:           map[keys.Record]string{keys.OSGIBundleVersion: "1.0.0"},
+	}, typed)
+
+	// the typed view converts back to an equivalent Record
+	require.Equal(t, record.Type(), typed.Record().Type())
+	require.Equal(t, record.Payload(), typed.Record().Payload())
+
+	_, err = record.AsArtifactRemove()
+	require.Error(t, err)
+	_, err = record.AsGroups()
+	require.Error(t, err)
+}
+
+func TestOtherTypedRecords(t *testing.T) {
+	logger := log.Default()
+
+	record, err := NewRecord(logger, map[string]string{
+		keys.Del:          "org.example|widget|1.0|NA",
+		RecordModifiedKey: "1243533417953",
+	})
+	require.NoError(t, err)
+	removed, err := record.AsArtifactRemove()
+	require.NoError(t, err)
+	require.Equal(t, ArtifactRemoveRecord{
+		RecordModified: time.UnixMilli(1243533417953).UTC(),
+		GroupID:        "org.example",
+		ArtifactID:     "widget",
+		Version:        "1.0",
+	}, removed)
+	require.Equal(t, record.Payload(), removed.Record().Payload())
+
+	record, err = NewRecord(logger, map[string]string{
+		keys.Descriptor: DescriptorValue,
+		IDXINFO:         "1.0|central",
+	})
+	require.NoError(t, err)
+	descriptor, err := record.AsDescriptor()
+	require.NoError(t, err)
+	require.Equal(t, DescriptorRecord{RepositoryID: "central", Version: "1.0"}, descriptor)
+	require.Equal(t, record.Payload(), descriptor.Record().Payload())
+
+	record, err = NewRecord(logger, map[string]string{
+		keys.RootGroups:     keys.RootGroups,
+		keys.RootGroupsList: "com|org",
+	})
+	require.NoError(t, err)
+	groups, err := record.AsGroups()
+	require.NoError(t, err)
+	require.Equal(t, GroupsRecord{Kind: RootGroups, Groups: []string{"com", "org"}}, groups)
+	require.Equal(t, RootGroups, groups.Record().Type())
+	require.Equal(t, record.Payload(), groups.Record().Payload())
+}
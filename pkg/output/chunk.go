@@ -0,0 +1,122 @@
+package output
+
+import (
+	"context"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+
+	"github.com/pkg/errors"
+)
+
+// the Records buffered per Chunk, so scans run ahead of the Sink
+const chunkBufferSize = 1024
+
+// Chunk - hands the Records scanned from one index chunk to Drive, and
+// reports back once the Sink has ended the chunk, or failed to
+type Chunk struct {
+	Meta ChunkMeta
+
+	records chan data.Record
+	scanned chan chunkScan
+	ended   chan error
+}
+
+// the result of a chunk scan
+type chunkScan struct {
+	timestamp time.Time
+	err       error
+}
+
+func NewChunk(meta ChunkMeta) *Chunk {
+	return &Chunk{
+		Meta:    meta,
+		records: make(chan data.Record, chunkBufferSize),
+		scanned: make(chan chunkScan, 1),
+		ended:   make(chan error, 1),
+	}
+}
+
+// Records - the channel the chunk's Records are published to, in order
+func (c *Chunk) Records() chan<- data.Record {
+	return c.records
+}
+
+// Finish - report every Record published, with the chunk timestamp read
+// from its header, or the error that ended the scan. No Records may follow
+func (c *Chunk) Finish(timestamp time.Time, err error) {
+	close(c.records)
+	c.scanned <- chunkScan{timestamp: timestamp, err: err}
+}
+
+// Ended - receives nil once the Sink has ended the chunk, or the error
+// from the scan or the Sink that prevented it
+func (c *Chunk) Ended() <-chan error {
+	return c.ended
+}
+
+// Drive - open the Sink, and write each Chunk received, in turn, between
+// BeginChunk and EndChunk, until chunks is closed. A failed scan leaves its
+// chunk unended, and the next follows; a failed Sink stops the drive. The
+// FlushRequests received are served between chunks. The Sink is closed
+// on return, abandoning the chunk in progress if the context is done
+func Drive(ctx context.Context, sink Sink, chunks <-chan *Chunk, flushes <-chan FlushRequest) error {
+	if err := sink.Open(ctx); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			sink.Close()
+			return errors.Wrap(ctx.Err(), "Output: cancelled with cause")
+
+		case req := <-flushes:
+			req <- sink.Flush()
+
+		case chunk, ok := <-chunks:
+			if !ok {
+				return sink.Close()
+			}
+
+			scanErr, sinkErr := writeChunk(ctx, sink, chunk)
+			if sinkErr != nil {
+				chunk.ended <- sinkErr
+				sink.Close()
+				return sinkErr
+			}
+			chunk.ended <- scanErr
+		}
+	}
+}
+
+// writeChunk - write the chunk's Records, then end it if its scan succeeded
+func writeChunk(ctx context.Context, sink Sink, chunk *Chunk) (scanErr, sinkErr error) {
+	if err := sink.BeginChunk(chunk.Meta); err != nil {
+		return nil, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "Output: cancelled with cause")
+
+		case record, ok := <-chunk.records:
+			if ok {
+				if err := sink.Write(record); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			scan := <-chunk.scanned
+			if scan.err != nil {
+				return scan.err, nil
+			}
+
+			meta := chunk.Meta
+			meta.Timestamp = scan.timestamp
+			return nil, sink.EndChunk(meta)
+		}
+	}
+}
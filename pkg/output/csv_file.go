@@ -1,87 +1,284 @@
 package output
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/elireisman/maven-index-reader-go/pkg/config"
 	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
 
 	"github.com/pkg/errors"
 )
 
+// the CSV column holding each row's RecordType
+const csvRecordTypeColumn = "record_type"
+
+// the key lists of each RecordType, in column order
+var csvRecordKeys = map[data.RecordType][]keys.Record{
+	data.ArtifactAdd:    data.ArtifactAddRecordKeys,
+	data.ArtifactRemove: data.ArtifactRemoveRecordKeys,
+	data.Descriptor:     data.DescriptorRecordKeys,
+	data.AllGroups:      data.AllGroupsRecordKeys,
+	data.RootGroups:     data.RootGroupsRecordKeys,
+}
+
+// CSVUnionColumns - the columns of config.UnionColumns output: the record
+// type, then the union of the key lists of every RecordType, in order
+func CSVUnionColumns() []string {
+	out := []string{csvRecordTypeColumn}
+	seen := map[string]bool{csvRecordTypeColumn: true}
+	for _, keyList := range [][]keys.Record{
+		data.ArtifactAddRecordKeys,
+		data.ArtifactRemoveRecordKeys,
+		data.DescriptorRecordKeys,
+		data.AllGroupsRecordKeys,
+		data.RootGroupsRecordKeys,
+	} {
+		for _, key := range keyList {
+			if !seen[key] {
+				out = append(out, key)
+				seen[key] = true
+			}
+		}
+	}
+
+	return out
+}
+
+// CSV - every row has the same columns as its file's header, whatever
+// the order the RecordTypes arrive in. Values absent from a Record, or
+// not applicable to its RecordType, are left empty
 type CSV struct {
 	logger *log.Logger
 	cfg    config.Index
-	input  <-chan data.Record
+	count  int
+
+	// per-type tables are opened as the first row for each is written
+	tables map[string]*csvTable
 }
 
-func NewCSV(l *log.Logger, in <-chan data.Record, c config.Index) CSV {
+func NewCSV(l *log.Logger, c config.Index) (*CSV, error) {
 	l.Printf("Output: formatting data.Records as CSV...\n")
-	return CSV{l, c, in}
+	out := &CSV{logger: l, cfg: c, tables: map[string]*csvTable{}}
+	if err := out.checkFields(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Open - a single file has its header even if no rows follow
+func (c *CSV) Open(_ context.Context) error {
+	if c.cfg.Output.CSV.Columns == config.PerTypeColumns {
+		return nil
+	}
+
+	table, err := openCSVTable(c.cfg.Output.File, c.columns(data.ArtifactAdd), c.cfg.Output.CSV.Delimiter)
+	if err != nil {
+		return err
+	}
+	c.tables[c.cfg.Output.File] = table
+	return nil
+}
+
+func (c *CSV) BeginChunk(_ ChunkMeta) error {
+	return nil
 }
 
-func (c CSV) Write() error {
-	var w *csv.Writer
-	if len(c.cfg.Output.File) > 0 {
-		path := filepath.Dir(c.cfg.Output.File)
-		err := os.MkdirAll(path, 0755)
+func (c *CSV) Write(record data.Record) error {
+	path := c.cfg.Output.File
+	if c.cfg.Output.CSV.Columns == config.PerTypeColumns {
+		path = perTypePath(path, data.RecordTypeNames[record.Type()])
+	}
+
+	table, found := c.tables[path]
+	if !found {
+		var err error
+		table, err = openCSVTable(path, c.columns(record.Type()), c.cfg.Output.CSV.Delimiter)
 		if err != nil {
-			return errors.Wrapf(err, "CSV: failed to create output directory at %s with cause", path)
+			return err
 		}
+		c.tables[path] = table
+	}
 
-		f, err := os.Create(c.cfg.Output.File)
-		if err != nil {
-			return errors.Wrapf(err, "CSV: failed to create output file at %s with cause", c.cfg.Output.File)
+	values := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		if column == csvRecordTypeColumn {
+			values[i] = data.RecordTypeNames[record.Type()]
+			continue
 		}
-		defer f.Close()
+		value := record.Get(column)
+		// plugin goals are kept in raw form, but written like the other lists
+		if raw, ok := value.(string); ok && column == keys.PluginGoals {
+			value = rawStrings(raw)
+		}
+		values[i] = c.formatValue(value)
+	}
 
-		w = csv.NewWriter(f)
-	} else {
-		w = csv.NewWriter(os.Stdout)
+	if err := table.w.Write(values); err != nil {
+		return errors.Wrapf(err, "CSV: failed to write values to file %s with cause", table.path)
 	}
-	defer w.Flush()
+	c.count++
 
-	count := 0
-	headersWritten := false
-	for record := range c.input {
-		if !headersWritten {
-			// obtain ordered list of keys, prefixed with RecordType
-			keys := []string{"record_type"}
-			keys = append(keys, record.Keys()...)
+	return nil
+}
 
-			if err := w.Write(keys); err != nil {
-				return errors.Wrapf(err, "CSV: failed to write headers to file %s with cause", c.cfg.Output.File)
-			}
-			headersWritten = true
+func (c *CSV) EndChunk(_ ChunkMeta) error {
+	return nil
+}
+
+func (c *CSV) Flush() error {
+	for _, table := range c.tables {
+		if err := table.flush(); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		// append data.Record's RecordType as 1st value
-		var values []string
-		values = append(values, data.RecordTypeNames[record.Type()])
+func (c *CSV) Close() error {
+	err := c.Flush()
+	for _, table := range c.tables {
+		table.close()
+	}
+	if err != nil {
+		return err
+	}
 
-		// collect remaining Record values in key-order
-		for i := 0; i < len(record.Keys()); i++ {
-			key := record.Keys()[i]
-			value := ""
+	c.logger.Printf("CSV: persisted %d records to file %s", c.count, c.cfg.Output.File)
+	return nil
+}
 
-			rawValue := record.Get(key)
-			if rawValue != nil {
-				value = fmt.Sprintf("%v", rawValue)
-			}
-			values = append(values, value)
+// columns - the header of the file rows of the RecordType are written to
+func (c *CSV) columns(rt data.RecordType) []string {
+	opts := c.cfg.Output.CSV
+	switch {
+	case len(opts.Fields) > 0:
+		return opts.Fields
+	case opts.Columns == config.PerTypeColumns:
+		return append([]string{csvRecordTypeColumn}, csvRecordKeys[rt]...)
+	default:
+		return CSVUnionColumns()
+	}
+}
+
+// checkFields - each selected field must be a known Record key
+func (c *CSV) checkFields() error {
+	known := map[string]bool{}
+	for _, column := range CSVUnionColumns() {
+		known[column] = true
+	}
+	for _, key := range data.OSGIRecordKeys {
+		known[key] = true
+	}
+
+	for _, field := range c.cfg.Output.CSV.Fields {
+		if !known[field] {
+			return errors.Errorf("CSV: unknown field %q selected", field)
 		}
+	}
 
-		if err := w.Write(values); err != nil {
-			return errors.Wrap(err, "CSV: failed to write values to file %s with cause")
+	return nil
+}
+
+// formatValue - encode a Record value as a CSV field
+func (c *CSV) formatValue(value interface{}) string {
+	opts := c.cfg.Output.CSV
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		layout := opts.TimeFormat
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		return v.UTC().Format(layout)
+	case []string:
+		sep := opts.ListSeparator
+		if sep == "" {
+			sep = data.RecordValueSeparator
 		}
+		return strings.Join(v, sep)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
 
-		count++
+// perTypePath - the output file for rows of the named RecordType, like
+// "dump.artifact_add.csv" for "dump.csv"
+func perTypePath(path, typeName string) string {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		ext = ".csv"
 	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + typeName + ext
+}
 
-	c.logger.Printf("CSV: successfully persisted %d records to file %s", count, c.cfg.Output.File)
+// csvTable - one CSV output file, and its columns
+type csvTable struct {
+	path    string
+	columns []string
+	f       *os.File
+	w       *csv.Writer
+}
+
+// openCSVTable - create the file at path, or use stdout
+// if unset, and write the header row of the columns
+func openCSVTable(path string, columns []string, delimiter rune) (*csvTable, error) {
+	table := &csvTable{path: path, columns: columns}
+
+	if len(path) > 0 {
+		dir := filepath.Dir(path)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, errors.Wrapf(err, "CSV: failed to create output directory at %s with cause", dir)
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "CSV: failed to create output file at %s with cause", path)
+		}
+		table.f = f
+		table.w = csv.NewWriter(f)
+	} else {
+		table.w = csv.NewWriter(os.Stdout)
+	}
+	if delimiter != 0 {
+		table.w.Comma = delimiter
+	}
+
+	if err := table.w.Write(columns); err != nil {
+		table.close()
+		return nil, errors.Wrapf(err, "CSV: failed to write headers to file %s with cause", path)
+	}
+
+	return table, nil
+}
+
+func (t *csvTable) flush() error {
+	t.w.Flush()
+	if err := t.w.Error(); err != nil {
+		return errors.Wrapf(err, "CSV: failed to flush file %s with cause", t.path)
+	}
 	return nil
 }
+
+func (t *csvTable) close() {
+	t.w.Flush()
+	if t.f != nil {
+		t.f.Close()
+	}
+}
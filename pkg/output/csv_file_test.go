@@ -0,0 +1,200 @@
+package output
+
+import (
+	"context"
+	"encoding/csv"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testCSVRecords(t *testing.T) []data.Record {
+	logger := log.Default()
+
+	var out []data.Record
+	for _, raw := range []map[string]string{
+		{keys.Descriptor: "NexusIndex", data.IDXINFO: "1.0|central"},
+		{keys.Del: "org.example|widget|0.9|NA|jar", data.RecordModifiedKey: "1243533417953"},
+		{
+			data.UInfoKey:          "org.example|widget|1.0|NA|jar",
+			data.InfoKey:           "jar|1243533415343|1024|1|0|1|jar",
+			data.RecordModifiedKey: "1243533417953",
+			data.ClassnamesKey:     "/org/example/Widget|/org/example/Gadget",
+		},
+	} {
+		record, err := data.NewRecord(logger, raw)
+		require.NoError(t, err)
+		out = append(out, record)
+	}
+
+	return out
+}
+
+func writeTestCSV(t *testing.T, opts config.CSVOptions) string {
+	cfg := config.Index{
+		Output: config.Output{
+			Format: config.CSV,
+			File:   filepath.Join(t.TempDir(), "out", "records.csv"),
+			CSV:    opts,
+		},
+	}
+
+	records := make(chan data.Record, 3)
+	for _, record := range testCSVRecords(t) {
+		records <- record
+	}
+	close(records)
+	require.NoError(t, ResolveFormat(log.Default(), records, cfg).WriteContext(context.Background()))
+
+	return cfg.Output.File
+}
+
+func readTestCSV(t *testing.T, path string, delimiter rune) []map[string]string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	if delimiter != 0 {
+		r.Comma = delimiter
+	}
+	rows, err := r.ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, rows)
+
+	// every row has exactly the header's columns
+	var out []map[string]string
+	for _, row := range rows[1:] {
+		require.Len(t, row, len(rows[0]))
+		byColumn := map[string]string{}
+		for i, column := range rows[0] {
+			byColumn[column] = row[i]
+		}
+		out = append(out, byColumn)
+	}
+	return out
+}
+
+func TestCSVUnionColumns(t *testing.T) {
+	path := writeTestCSV(t, config.CSVOptions{})
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	header, err := csv.NewReader(f).Read()
+	f.Close()
+	require.NoError(t, err)
+	require.Equal(t, CSVUnionColumns(), header)
+
+	// the descriptor arriving first doesn't determine the columns
+	rows := readTestCSV(t, path, 0)
+	require.Len(t, rows, 3)
+	require.Equal(t, "descriptor", rows[0]["record_type"])
+	require.Equal(t, "central", rows[0][keys.RepositoryID])
+	require.Equal(t, "1.0", rows[0][keys.Version])
+	require.Equal(t, "artifact_remove", rows[1]["record_type"])
+	require.Equal(t, "0.9", rows[1][keys.Version])
+	require.Equal(t, "", rows[1][keys.SHA1])
+	require.Equal(t, "artifact_add", rows[2]["record_type"])
+	require.Equal(t, "widget", rows[2][keys.ArtifactID])
+	require.Equal(t, "1024", rows[2][keys.FileSize])
+	require.Equal(t, "true", rows[2][keys.HasSources])
+	require.Equal(t, "2009-05-28T17:56:57.953Z", rows[2][keys.RecordModified])
+	require.Equal(t, "/org/example/Widget|/org/example/Gadget", rows[2][keys.Classnames])
+}
+
+func TestCSVFieldsAndEncoding(t *testing.T) {
+	opts := config.CSVOptions{
+		Fields:        []string{keys.ArtifactID, "record_type", keys.RecordModified, keys.Classnames},
+		Delimiter:     ';',
+		ListSeparator: " ",
+		TimeFormat:    "2006-01-02",
+	}
+	path := writeTestCSV(t, opts)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "artifactId;record_type;recordModified;classNames\n"+
+		";descriptor;;\n"+
+		"widget;artifact_remove;2009-05-28;\n"+
+		"widget;artifact_add;2009-05-28;/org/example/Widget /org/example/Gadget\n", string(content))
+
+	// unknown fields are rejected
+	cfg := config.Index{
+		Output: config.Output{
+			Format: config.CSV,
+			File:   filepath.Join(t.TempDir(), "records.csv"),
+			CSV:    config.CSVOptions{Fields: []string{"artifact"}},
+		},
+	}
+	records := make(chan data.Record)
+	close(records)
+	require.Error(t, ResolveFormat(log.Default(), records, cfg).Write())
+}
+
+func TestCSVPerTypeColumns(t *testing.T) {
+	path := writeTestCSV(t, config.CSVOptions{Columns: config.PerTypeColumns})
+
+	// no rows are written to the configured file itself
+	_, err := os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	dir := filepath.Dir(path)
+	for name, columns := range map[string][]string{
+		"records.descriptor.csv":      append([]string{"record_type"}, data.DescriptorRecordKeys...),
+		"records.artifact_remove.csv": append([]string{"record_type"}, data.ArtifactRemoveRecordKeys...),
+		"records.artifact_add.csv":    append([]string{"record_type"}, data.ArtifactAddRecordKeys...),
+	} {
+		f, err := os.Open(filepath.Join(dir, name))
+		require.NoError(t, err)
+		rows, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		require.NoError(t, err)
+		require.Len(t, rows, 2, name)
+		require.Equal(t, columns, rows[0], name)
+	}
+}
+
+func TestCSVPluginGoals(t *testing.T) {
+	record, err := data.NewRecord(log.Default(), map[string]string{
+		data.UInfoKey:          "org.example|example-maven-plugin|1.0|NA|jar",
+		data.InfoKey:           "maven-plugin|1243533415343|1024|1|0|1|jar",
+		data.RecordModifiedKey: "1243533417953",
+		"px":                   "example",
+		"gx":                   "generate|verify",
+	})
+	require.NoError(t, err)
+
+	// plugin goals are joined with the list separator, like other list fields
+	for sep, expected := range map[string]string{
+		"":  "generate|verify",
+		" ": "generate verify",
+		",": "generate,verify",
+	} {
+		cfg := config.Index{
+			Output: config.Output{
+				Format: config.CSV,
+				File:   filepath.Join(t.TempDir(), "plugins.csv"),
+				CSV: config.CSVOptions{
+					Fields:        []string{keys.PluginPrefix, keys.PluginGoals},
+					ListSeparator: sep,
+				},
+			},
+		}
+		records := make(chan data.Record, 1)
+		records <- record
+		close(records)
+		require.NoError(t, ResolveFormat(log.Default(), records, cfg).WriteContext(context.Background()))
+
+		rows := readTestCSV(t, cfg.Output.File, 0)
+		require.Len(t, rows, 1)
+		require.Equal(t, "example", rows[0][keys.PluginPrefix])
+		require.Equal(t, expected, rows[0][keys.PluginGoals], "separator %q", sep)
+	}
+}
@@ -2,6 +2,7 @@ package output
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"log"
 	"os"
@@ -13,64 +14,98 @@ import (
 	"github.com/pkg/errors"
 )
 
+// JSON - writes a single JSON array of Record objects
 type JSON struct {
 	logger *log.Logger
 	cfg    config.Index
-	input  <-chan data.Record
+	f      *os.File
+	w      *bufio.Writer
+	count  int
 }
 
-func NewJSON(l *log.Logger, in <-chan data.Record, c config.Index) JSON {
+func NewJSON(l *log.Logger, c config.Index) *JSON {
 	l.Printf("Output: formatting data.Records as JSON...\n")
-	return JSON{l, c, in}
+	return &JSON{logger: l, cfg: c}
 }
 
-func (j JSON) Write() error {
-	var w *bufio.Writer
-	if len(j.cfg.Output.File) > 0 {
-		path := filepath.Dir(j.cfg.Output.File)
-		err := os.MkdirAll(path, 0755)
-		if err != nil {
-			return errors.Wrapf(err, "JSON: failed to create output directory at %s with cause", path)
-		}
+func (j *JSON) Open(_ context.Context) error {
+	var err error
+	if j.f, j.w, err = createOutputFile("JSON", j.cfg.Output.File); err != nil {
+		return err
+	}
 
-		f, err := os.Create(j.cfg.Output.File)
-		if err != nil {
-			return errors.Wrapf(err, "JSON: failed to create output file at %s with cause", j.cfg.Output.File)
-		}
-		defer f.Close()
+	if _, err := j.w.WriteString("[\n"); err != nil {
+		return errors.Wrapf(err, "JSON: failed initial write to output file %s with cause", j.cfg.Output.File)
+	}
+	return nil
+}
+
+func (j *JSON) BeginChunk(_ ChunkMeta) error {
+	return nil
+}
 
-		w = bufio.NewWriter(f)
-	} else {
-		w = bufio.NewWriter(os.Stdout)
+func (j *JSON) Write(record data.Record) error {
+	if j.count > 0 {
+		if _, err := j.w.WriteString(",\n"); err != nil {
+			return errors.Wrapf(err, "JSON: failed to write Record %d to output file %s with cause", j.count+1, j.cfg.Output.File)
+		}
 	}
-	defer w.Flush()
 
-	_, err := w.WriteString("[\n")
+	out, err := json.Marshal(jsonObject(record))
 	if err != nil {
-		return errors.Wrapf(err, "JSON: failed initial write to output file %s with cause", j.cfg.Output.File)
+		return errors.Wrapf(err, "JSON: failed to encode Record %d to output file %s with cause", j.count+1, j.cfg.Output.File)
 	}
+	if _, err := j.w.Write(out); err != nil {
+		return errors.Wrapf(err, "JSON: failed to write Record %d to output file %s with cause", j.count+1, j.cfg.Output.File)
+	}
+	j.count++
 
-	count := 0
-	for record := range j.input {
-		if count > 0 {
-			w.WriteString(",\n")
-		}
+	return nil
+}
+
+func (j *JSON) EndChunk(_ ChunkMeta) error {
+	return nil
+}
 
-		// inject record's RecordType into payload prior to JSON  serialization
-		record.Payload()["recordType"] = data.RecordTypeNames[record.Type()]
+func (j *JSON) Flush() error {
+	if err := j.w.Flush(); err != nil {
+		return errors.Wrapf(err, "JSON: failed to flush output file %s with cause", j.cfg.Output.File)
+	}
+	return nil
+}
 
-		out, err := json.Marshal(record.Payload())
-		if err != nil {
-			return errors.Wrapf(err, "JSON: failed to encode Record %d to output file %s with cause", count+1, j.cfg.Output.File)
-		}
-		_, err = w.Write(out)
-		if err != nil {
-			return errors.Wrapf(err, "JSON: failed to write Record %d to output file %s with cause", count+1, j.cfg.Output.File)
-		}
-		count++
+// Close - closes the array, even if cancelled, so the output remains well-formed
+func (j *JSON) Close() error {
+	if j.f != nil {
+		defer j.f.Close()
+	}
+
+	if _, err := j.w.WriteString("\n]"); err != nil {
+		return errors.Wrapf(err, "JSON: failed final write to output file %s with cause", j.cfg.Output.File)
+	}
+	if err := j.Flush(); err != nil {
+		return err
 	}
-	w.WriteString("\n]")
 
-	j.logger.Printf("JSON: successfully persisted %d records to file %s", count, j.cfg.Output.File)
+	j.logger.Printf("JSON: persisted %d records to file %s", j.count, j.cfg.Output.File)
 	return nil
 }
+
+// createOutputFile - create the file at path, or use stdout if unset
+func createOutputFile(format, path string) (*os.File, *bufio.Writer, error) {
+	if len(path) == 0 {
+		return nil, bufio.NewWriter(os.Stdout), nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, errors.Wrapf(err, "%s: failed to create output directory at %s with cause", format, dir)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "%s: failed to create output file at %s with cause", format, path)
+	}
+
+	return f, bufio.NewWriter(f), nil
+}
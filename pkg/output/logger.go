@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -8,23 +9,40 @@ import (
 	"github.com/elireisman/maven-index-reader-go/pkg/data"
 )
 
+// Logger - prints unformatted Go structs to stdout, which is unbuffered
 type Logger struct {
 	logger *log.Logger
-	input  <-chan data.Record
+	count  int
 }
 
-func NewLogger(l *log.Logger, in <-chan data.Record, _ config.Index) Logger {
+func NewLogger(l *log.Logger, _ config.Index) *Logger {
 	l.Printf("Output: printing data.Record structs to stdout...")
-	return Logger{l, in}
+	return &Logger{logger: l}
 }
 
-func (l Logger) Write() error {
-	count := 0
-	for record := range l.input {
-		fmt.Printf("%+v\n", record)
-		count++
-	}
+func (l *Logger) Open(_ context.Context) error {
+	return nil
+}
+
+func (l *Logger) BeginChunk(_ ChunkMeta) error {
+	return nil
+}
+
+func (l *Logger) Write(record data.Record) error {
+	fmt.Printf("%+v\n", record)
+	l.count++
+	return nil
+}
+
+func (l *Logger) EndChunk(_ ChunkMeta) error {
+	return nil
+}
+
+func (l *Logger) Flush() error {
+	return nil
+}
 
-	l.logger.Printf("Logger: completed print of %d records", count)
+func (l *Logger) Close() error {
+	l.logger.Printf("Logger: completed print of %d records", l.count)
 	return nil
 }
@@ -0,0 +1,91 @@
+package output
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+
+	"github.com/pkg/errors"
+)
+
+// NDJSON - writes one JSON object per line, each conforming to the
+// versioned JSON Schema returned by Schema. Every line is complete once
+// written, so the output can be tailed, and survives a run dying midway
+type NDJSON struct {
+	logger *log.Logger
+	cfg    config.Index
+	f      *os.File
+	w      *bufio.Writer
+	count  int
+}
+
+func NewNDJSON(l *log.Logger, c config.Index) *NDJSON {
+	l.Printf("Output: formatting data.Records as newline-delimited JSON...\n")
+	return &NDJSON{logger: l, cfg: c}
+}
+
+func (n *NDJSON) Open(_ context.Context) error {
+	var err error
+	n.f, n.w, err = createOutputFile("NDJSON", n.cfg.Output.File)
+	return err
+}
+
+func (n *NDJSON) BeginChunk(_ ChunkMeta) error {
+	return nil
+}
+
+func (n *NDJSON) Write(record data.Record) error {
+	obj := jsonObject(record)
+	obj[SchemaVersionKey] = SchemaVersion
+
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrapf(err, "NDJSON: failed to encode Record %d to output file %s with cause", n.count+1, n.cfg.Output.File)
+	}
+	if _, err := n.w.Write(append(out, '\n')); err != nil {
+		return errors.Wrapf(err, "NDJSON: failed to write Record %d to output file %s with cause", n.count+1, n.cfg.Output.File)
+	}
+	n.count++
+
+	return nil
+}
+
+func (n *NDJSON) EndChunk(_ ChunkMeta) error {
+	return nil
+}
+
+func (n *NDJSON) Flush() error {
+	if err := n.w.Flush(); err != nil {
+		return errors.Wrapf(err, "NDJSON: failed to flush output file %s with cause", n.cfg.Output.File)
+	}
+	return nil
+}
+
+func (n *NDJSON) Close() error {
+	if n.f != nil {
+		defer n.f.Close()
+	}
+	if err := n.Flush(); err != nil {
+		return err
+	}
+
+	n.logger.Printf("NDJSON: persisted %d records to file %s", n.count, n.cfg.Output.File)
+	return nil
+}
+
+// jsonObject - a copy of the Record's payload, with its RecordType
+// injected, leaving the Record itself unchanged
+func jsonObject(record data.Record) map[string]interface{} {
+	out := make(map[string]interface{}, len(record.Payload())+2)
+	for key, value := range record.Payload() {
+		out[key] = value
+	}
+	out[RecordTypeKey] = data.RecordTypeNames[record.Type()]
+
+	return out
+}
@@ -0,0 +1,105 @@
+package output
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNDJSON(t *testing.T) {
+	logger := log.Default()
+
+	add, err := data.NewRecord(logger, map[string]string{
+		data.UInfoKey:          "org.example|widget|1.0|NA|jar",
+		data.InfoKey:           "jar|1243533415343|1024|1|0|1|jar",
+		data.RecordModifiedKey: "1243533417953",
+		data.SHA1Key:           "38bb5a445e9aa5a38581743ede58f46c0f1ce321",
+		data.ClassnamesKey:     "/org/example/Widget|/org/example/Gadget",
+	})
+	require.NoError(t, err)
+	remove, err := data.NewRecord(logger, map[string]string{
+		keys.Del:               "org.example|widget|0.9|NA|jar",
+		data.RecordModifiedKey: "1243533417953",
+	})
+	require.NoError(t, err)
+
+	cfg := config.Index{
+		Output: config.Output{
+			Format: config.NDJSON,
+			File:   filepath.Join(t.TempDir(), "out", "records.ndjson"),
+		},
+	}
+	records := make(chan data.Record, 2)
+	records <- add
+	records <- remove
+	close(records)
+	require.NoError(t, ResolveFormat(logger, records, cfg).WriteContext(context.Background()))
+
+	// the records themselves are left unchanged
+	require.NotContains(t, add.Payload(), RecordTypeKey)
+	require.NotContains(t, add.Payload(), SchemaVersionKey)
+
+	// every field written is described by the schema for its record type
+	schema, err := Schema()
+	require.NoError(t, err)
+	var doc struct {
+		OneOf []struct {
+			Title      string                 `json:"title"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"oneOf"`
+	}
+	require.NoError(t, json.Unmarshal(schema, &doc))
+	properties := map[string]map[string]interface{}{}
+	for _, variant := range doc.OneOf {
+		properties[variant.Title] = variant.Properties
+	}
+
+	f, err := os.Open(cfg.Output.File)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+
+		require.Equal(t, float64(SchemaVersion), line[SchemaVersionKey])
+		variant, ok := properties[line[RecordTypeKey].(string)]
+		require.True(t, ok)
+		for key := range line {
+			require.Contains(t, variant, key)
+		}
+	}
+	require.NoError(t, scanner.Err())
+	require.Len(t, lines, 2)
+
+	require.Equal(t, "artifact_add", lines[0][RecordTypeKey])
+	require.Equal(t, "widget", lines[0][keys.ArtifactID])
+	require.Equal(t, float64(1024), lines[0][keys.FileSize])
+	require.Equal(t, "2009-05-28T17:56:57.953Z", lines[0][keys.RecordModified])
+	require.Equal(t, []interface{}{"/org/example/Widget", "/org/example/Gadget"}, lines[0][keys.Classnames])
+	require.Equal(t, "artifact_remove", lines[1][RecordTypeKey])
+	require.Equal(t, "0.9", lines[1][keys.Version])
+}
+
+func TestSchemaDocument(t *testing.T) {
+	schema, err := Schema()
+	require.NoError(t, err)
+
+	// regenerate with "make schema"
+	published, err := os.ReadFile("../../docs/ndjson-schema.json")
+	require.NoError(t, err)
+	require.Equal(t, string(schema), string(published))
+}
@@ -1,29 +1,101 @@
 package output
 
 import (
+	"context"
 	"log"
 
 	"github.com/elireisman/maven-index-reader-go/pkg/config"
 	"github.com/elireisman/maven-index-reader-go/pkg/data"
+
+	"github.com/pkg/errors"
 )
 
-// Format - contract for supported ouput formats
+// Format - writes the Records received on a queue to a Sink, without chunk
+// boundaries. Drive a Sink directly to commit and checkpoint per chunk
 type Format interface {
 	Write() error
+
+	// WriteContext - as Write, but stops consuming once the context is
+	// done, flushing records already consumed before returning its error
+	WriteContext(ctx context.Context) error
 }
 
-// ResolveFormat -
+// FlushRequest - asks a running Format or Drive to flush the records
+// consumed so far, as before checkpointing in a long-running watch. The
+// Sink's result is sent back on the channel, once flushed
+type FlushRequest chan error
+
+// ResolveFormat - the Format writing the queue to the Sink
+// registered under the configured output's name
 func ResolveFormat(logger *log.Logger, queue <-chan data.Record, cfg config.Index) Format {
-	var out Format
-
-	switch cfg.Output.Format {
-	case config.JSON:
-		out = NewJSON(logger, queue, cfg)
-	case config.CSV:
-		out = NewCSV(logger, queue, cfg)
-	default: // log unformatted Go structs
-		out = NewLogger(logger, queue, cfg)
+	return ResolveFlushingFormat(logger, queue, nil, cfg)
+}
+
+// ResolveFlushingFormat - as ResolveFormat, but the Format also serves
+// the FlushRequests received on flushes while it writes
+func ResolveFlushingFormat(logger *log.Logger, queue <-chan data.Record, flushes <-chan FlushRequest, cfg config.Index) Format {
+	if flushes != nil {
+		// flushed Parquet rows are only readable from a completed file
+		cfg.Output.Parquet.RollOnFlush = true
+	}
+
+	sink, err := NewSink(logger, cfg)
+	return sinkFormat{sink: sink, err: err, input: queue, flushes: flushes}
+}
+
+// sinkFormat - a Format over a Sink, or the error constructing it
+type sinkFormat struct {
+	sink    Sink
+	err     error
+	input   <-chan data.Record
+	flushes <-chan FlushRequest
+}
+
+func (sf sinkFormat) Write() error {
+	return sf.WriteContext(context.Background())
+}
+
+func (sf sinkFormat) WriteContext(ctx context.Context) error {
+	if sf.err != nil {
+		return sf.err
+	}
+	if err := sf.sink.Open(ctx); err != nil {
+		return err
+	}
+
+	for {
+		record, ok, err := nextRecord(ctx, sf.input, sf.flushes, sf.sink.Flush)
+		if err != nil {
+			if closeErr := sf.sink.Close(); closeErr != nil {
+				return closeErr
+			}
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		if err := sf.sink.Write(record); err != nil {
+			sf.sink.Close()
+			return err
+		}
 	}
 
-	return out
+	return sf.sink.Close()
+}
+
+// obtain the next data.Record from the input queue, serving any FlushRequests
+// meanwhile. Returns false once the queue is closed and drained, or an error
+// if the context is done
+func nextRecord(ctx context.Context, in <-chan data.Record, flushes <-chan FlushRequest, flush func() error) (data.Record, bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return data.Record{}, false, errors.Wrap(ctx.Err(), "Output: cancelled with cause")
+		case req := <-flushes:
+			req <- flush()
+		case record, ok := <-in:
+			return record, ok, nil
+		}
+	}
 }
@@ -0,0 +1,306 @@
+package output
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/pkg/errors"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// the Parquet column holding each row's RecordType
+const parquetRecordTypeColumn = "recordType"
+
+// the Parquet type of UTF8 string columns, and of each ArtifactAdd key that isn't one
+const parquetStringType = "type=BYTE_ARRAY, convertedtype=UTF8"
+
+var parquetColumnTypes = map[keys.Record]string{
+	keys.RecordModified: "type=INT64, convertedtype=TIMESTAMP_MILLIS",
+	keys.FileModified:   "type=INT64, convertedtype=TIMESTAMP_MILLIS",
+	keys.FileSize:       "type=INT64",
+	keys.HasSources:     "type=BOOLEAN",
+	keys.HasJavadoc:     "type=BOOLEAN",
+	keys.HasSignature:   "type=BOOLEAN",
+	keys.Classnames:     "type=LIST, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8",
+	keys.PluginGoals:    "type=LIST, valuetype=BYTE_ARRAY, valueconvertedtype=UTF8",
+}
+
+// parquetRow - the Go struct type parquet-go derives the file schema from:
+// a required record type column, then an optional column per key of
+// data.ArtifactAddRecordKeys, in order. ArtifactRemove keys are a subset
+var parquetRow = func() reflect.Type {
+	fields := []reflect.StructField{{
+		Name: "Field0",
+		Type: reflect.TypeOf(""),
+		Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"name=%s, %s"`, parquetRecordTypeColumn, parquetStringType)),
+	}}
+
+	for i, key := range data.ArtifactAddRecordKeys {
+		columnType, ok := parquetColumnTypes[key]
+		if !ok {
+			columnType = parquetStringType
+		}
+
+		var goType reflect.Type
+		switch {
+		case strings.Contains(columnType, "type=LIST"):
+			goType = reflect.TypeOf([]string{})
+		case strings.Contains(columnType, "type=INT64"):
+			goType = reflect.TypeOf((*int64)(nil))
+		case strings.Contains(columnType, "type=BOOLEAN"):
+			goType = reflect.TypeOf((*bool)(nil))
+		default:
+			goType = reflect.TypeOf((*string)(nil))
+		}
+
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("Field%d", i+1),
+			Type: goType,
+			Tag:  reflect.StructTag(fmt.Sprintf(`parquet:"name=%s, %s"`, key, columnType)),
+		})
+	}
+
+	return reflect.StructOf(fields)
+}()
+
+// Parquet - writes ArtifactAdd and ArtifactRemove Records as rows of
+// Parquet files, skipping other RecordTypes. Absent values are null,
+// and absent class name or plugin goal lists are empty. A Parquet file
+// is only readable once its footer is written on close, so when rolling
+// on flush, each Flush closes the current file, and following rows go
+// to the next part. Part numbers resume after the highest already on
+// disk, leaving the files of earlier runs intact
+type Parquet struct {
+	logger *log.Logger
+	cfg    config.Index
+
+	rolling bool
+	file    *parquetFile
+	part    int
+	count   int
+	skipped int
+}
+
+func NewParquet(l *log.Logger, c config.Index) *Parquet {
+	l.Printf("Output: formatting data.Records as Parquet...\n")
+	opts := c.Output.Parquet
+	return &Parquet{logger: l, cfg: c, rolling: opts.RowsPerFile > 0 || opts.RollOnFlush}
+}
+
+// Open - a single file is written even if no rows follow
+func (p *Parquet) Open(_ context.Context) error {
+	if p.rolling {
+		return nil
+	}
+	return p.open()
+}
+
+func (p *Parquet) BeginChunk(_ ChunkMeta) error {
+	return nil
+}
+
+func (p *Parquet) Write(record data.Record) error {
+	if record.Type() != data.ArtifactAdd && record.Type() != data.ArtifactRemove {
+		if p.cfg.Verbose {
+			p.logger.Printf("Parquet: skipping %s record without a row schema", data.RecordTypeNames[record.Type()])
+		}
+		p.skipped++
+		return nil
+	}
+
+	if p.file == nil {
+		if err := p.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.file.w.Write(parquetRowOf(record).Interface()); err != nil {
+		return errors.Wrapf(err, "Parquet: failed to write Record %d to file %s with cause", p.count+1, p.file.path)
+	}
+	p.file.rows++
+	p.count++
+
+	if limit := p.cfg.Output.Parquet.RowsPerFile; limit > 0 && p.file.rows >= limit {
+		return p.roll()
+	}
+	return nil
+}
+
+func (p *Parquet) EndChunk(_ ChunkMeta) error {
+	return nil
+}
+
+// Flush - completes the current file if rolling on flush, and otherwise
+// writes the rows buffered as a row group, readable once the file closes
+func (p *Parquet) Flush() error {
+	if p.cfg.Output.Parquet.RollOnFlush {
+		return p.roll()
+	}
+	if p.file == nil {
+		return nil
+	}
+
+	if err := p.file.w.Flush(true); err != nil {
+		return errors.Wrapf(err, "Parquet: failed to write row group to file %s with cause", p.file.path)
+	}
+	if err := p.file.buf.Flush(); err != nil {
+		return errors.Wrapf(err, "Parquet: failed to flush file %s with cause", p.file.path)
+	}
+	return nil
+}
+
+func (p *Parquet) Close() error {
+	if err := p.roll(); err != nil {
+		return err
+	}
+
+	p.logger.Printf("Parquet: persisted %d records (skipped %d) to file %s", p.count, p.skipped, p.cfg.Output.File)
+	return nil
+}
+
+// open - the output file, or when rolling, the next unused part
+func (p *Parquet) open() error {
+	path := p.cfg.Output.File
+	if p.rolling {
+		path, p.part = nextParquetPart(path, p.part)
+		p.part++
+	}
+
+	var err error
+	p.file, err = openParquetFile(path, p.cfg.Output.Parquet.RowGroupSize)
+	return err
+}
+
+// roll - complete the current file, if any
+func (p *Parquet) roll() error {
+	if p.file == nil {
+		return nil
+	}
+	err := p.file.close()
+	p.file = nil
+	return err
+}
+
+// parquetRowOf - the Record's values, converted to their column's Go type
+func parquetRowOf(record data.Record) reflect.Value {
+	row := reflect.New(parquetRow).Elem()
+	row.Field(0).SetString(data.RecordTypeNames[record.Type()])
+
+	for i, key := range data.ArtifactAddRecordKeys {
+		field := row.Field(i + 1)
+
+		switch v := record.Get(key).(type) {
+		case string:
+			if field.Kind() == reflect.Slice {
+				field.Set(reflect.ValueOf(rawStrings(v)))
+				continue
+			}
+			field.Set(reflect.ValueOf(&v))
+		case time.Time:
+			millis := v.UnixMilli()
+			field.Set(reflect.ValueOf(&millis))
+		case int64:
+			field.Set(reflect.ValueOf(&v))
+		case bool:
+			field.Set(reflect.ValueOf(&v))
+		case []string:
+			field.Set(reflect.ValueOf(v))
+		}
+	}
+
+	return row
+}
+
+// rawStrings - a list value kept in raw form, like plugin goals,
+// split as data.Record's typed views do
+func rawStrings(raw string) []string {
+	if len(strings.TrimSpace(raw)) == 0 {
+		return nil
+	}
+
+	values := strings.Split(raw, data.RecordValueSeparator)
+	for i, value := range values {
+		values[i] = strings.Trim(value, " \t\r\n")
+	}
+	return values
+}
+
+// nextParquetPart - the first unused part path numbered from part, like
+// "dump-00003.parquet" for "dump.parquet", and its part number
+func nextParquetPart(path string, part int) (string, int) {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		ext = ".parquet"
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+
+	for {
+		candidate := fmt.Sprintf("%s-%05d%s", base, part, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate, part
+		}
+		part++
+	}
+}
+
+// parquetFile - one Parquet output file, and the rows written to it
+type parquetFile struct {
+	path string
+	rows int64
+	f    *os.File
+	buf  *bufio.Writer
+	w    *writer.ParquetWriter
+}
+
+// openParquetFile - create the file at path, and write its header
+func openParquetFile(path string, rowGroupSize int64) (*parquetFile, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "Parquet: failed to create output directory at %s with cause", dir)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Parquet: failed to create output file at %s with cause", path)
+	}
+
+	buf := bufio.NewWriter(f)
+	w, err := writer.NewParquetWriterFromWriter(buf, reflect.New(parquetRow).Interface(), 1)
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "Parquet: failed to initialize output file %s with cause", path)
+	}
+	if rowGroupSize > 0 {
+		w.RowGroupSize = rowGroupSize
+	}
+
+	return &parquetFile{path: path, f: f, buf: buf, w: w}, nil
+}
+
+// close - write the footer, completing the file
+func (pf *parquetFile) close() error {
+	defer pf.f.Close()
+
+	if err := pf.w.WriteStop(); err != nil {
+		return errors.Wrapf(err, "Parquet: failed to write footer of file %s with cause", pf.path)
+	}
+	if err := pf.buf.Flush(); err != nil {
+		return errors.Wrapf(err, "Parquet: failed to flush file %s with cause", pf.path)
+	}
+	if err := pf.f.Sync(); err != nil {
+		return errors.Wrapf(err, "Parquet: failed to sync file %s with cause", pf.path)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package output
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// testParquetFile - a local source.ParquetFile to read output back with.
+// The reader reopens it by an empty name, per column
+type testParquetFile struct {
+	*os.File
+}
+
+func (tpf testParquetFile) Open(name string) (source.ParquetFile, error) {
+	if name == "" {
+		name = tpf.Name()
+	}
+	f, err := os.Open(name)
+	return testParquetFile{f}, err
+}
+
+func (tpf testParquetFile) Create(name string) (source.ParquetFile, error) {
+	f, err := os.Create(name)
+	return testParquetFile{f}, err
+}
+
+// readTestParquet - the file's rows, and its schema by column name
+func readTestParquet(t *testing.T, path string) (map[string]*parquet.SchemaElement, reflect.Value) {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, err := reader.NewParquetReader(testParquetFile{f}, reflect.New(parquetRow).Interface(), 1)
+	require.NoError(t, err)
+	defer r.ReadStop()
+
+	rows := reflect.New(reflect.SliceOf(parquetRow))
+	rows.Elem().Set(reflect.MakeSlice(reflect.SliceOf(parquetRow), int(r.GetNumRows()), int(r.GetNumRows())))
+	require.NoError(t, r.Read(rows.Interface()))
+
+	// the reader renames the schema to the Go field names
+	columns := map[string]*parquet.SchemaElement{}
+	for i, element := range r.SchemaHandler.SchemaElements {
+		columns[r.SchemaHandler.Infos[i].ExName] = element
+	}
+
+	return columns, rows.Elem()
+}
+
+func TestParquet(t *testing.T) {
+	cfg := config.Index{
+		Output: config.Output{
+			Format:  config.Parquet,
+			File:    filepath.Join(t.TempDir(), "out", "records.parquet"),
+			Parquet: config.ParquetOptions{RowsPerFile: 1},
+		},
+	}
+
+	records := make(chan data.Record, 3)
+	for _, record := range testCSVRecords(t) {
+		records <- record
+	}
+	close(records)
+	require.NoError(t, ResolveFormat(log.Default(), records, cfg).WriteContext(context.Background()))
+
+	// the descriptor is skipped, and each remaining row rolls to its own file
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(cfg.Output.File), "*.parquet"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(filepath.Dir(cfg.Output.File), "records-00000.parquet"),
+		filepath.Join(filepath.Dir(cfg.Output.File), "records-00001.parquet"),
+	}, matches)
+
+	columns, rows := readTestParquet(t, matches[0])
+	require.Equal(t, 1, rows.Len())
+	row := rows.Index(0)
+	require.Equal(t, "artifact_remove", row.Field(0).String())
+	require.Equal(t, "org.example", row.FieldByName("Field2").Elem().String())
+	require.True(t, row.FieldByName("Field8").IsNil(), "fileSize of a removal is null")
+
+	require.Equal(t, parquet.Type_BYTE_ARRAY, columns["recordType"].GetType())
+	require.Equal(t, parquet.ConvertedType_TIMESTAMP_MILLIS, columns["recordModified"].GetConvertedType())
+	require.Equal(t, parquet.ConvertedType_TIMESTAMP_MILLIS, columns["fileModified"].GetConvertedType())
+	require.Equal(t, parquet.Type_INT64, columns["fileSize"].GetType())
+	require.Equal(t, parquet.Type_BOOLEAN, columns["hasSources"].GetType())
+	require.Equal(t, parquet.ConvertedType_LIST, columns["classNames"].GetConvertedType())
+
+	_, rows = readTestParquet(t, matches[1])
+	require.Equal(t, 1, rows.Len())
+	row = rows.Index(0)
+	require.Equal(t, "artifact_add", row.Field(0).String())
+	require.Equal(t, int64(1243533417953), row.Field(1).Elem().Int())
+	require.Equal(t, int64(1243533415343), row.FieldByName("Field7").Elem().Int())
+	require.Equal(t, int64(1024), row.FieldByName("Field8").Elem().Int())
+	require.True(t, row.FieldByName("Field10").Elem().Bool())
+	require.False(t, row.FieldByName("Field11").Elem().Bool())
+	require.Equal(t, []string{"/org/example/Widget", "/org/example/Gadget"}, row.FieldByName("Field16").Interface())
+}
+
+func TestParquetFlushRollsFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Index{Output: config.Output{Format: config.Parquet, File: filepath.Join(dir, "records.parquet")}}
+
+	// a part left by an earlier run is kept
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "records-00000.parquet"), []byte("earlier"), 0o644))
+
+	records := make(chan data.Record)
+	flushes := make(chan FlushRequest)
+	done := make(chan error)
+	go func() {
+		done <- ResolveFlushingFormat(log.Default(), records, flushes, cfg).WriteContext(context.Background())
+	}()
+
+	// a flush completes the file, readable while the output runs on
+	records <- testCSVRecords(t)[2]
+	req := make(FlushRequest)
+	flushes <- req
+	require.NoError(t, <-req)
+
+	_, rows := readTestParquet(t, filepath.Join(dir, "records-00001.parquet"))
+	require.Equal(t, 1, rows.Len())
+
+	records <- testCSVRecords(t)[1]
+	close(records)
+	require.NoError(t, <-done)
+
+	_, rows = readTestParquet(t, filepath.Join(dir, "records-00002.parquet"))
+	require.Equal(t, 1, rows.Len())
+	earlier, err := os.ReadFile(filepath.Join(dir, "records-00000.parquet"))
+	require.NoError(t, err)
+	require.Equal(t, "earlier", string(earlier))
+}
+
+func TestParquetPluginGoals(t *testing.T) {
+	cfg := config.Index{
+		Output: config.Output{
+			Format: config.Parquet,
+			File:   filepath.Join(t.TempDir(), "plugins.parquet"),
+		},
+	}
+
+	record, err := data.NewRecord(log.Default(), map[string]string{
+		data.UInfoKey:          "org.example|example-maven-plugin|1.0|NA|jar",
+		data.InfoKey:           "maven-plugin|1243533415343|1024|1|0|1|jar",
+		data.RecordModifiedKey: "1243533417953",
+		"px":                   "example",
+		"gx":                   "generate|verify",
+	})
+	require.NoError(t, err)
+
+	records := make(chan data.Record, 1)
+	records <- record
+	close(records)
+	require.NoError(t, ResolveFormat(log.Default(), records, cfg).WriteContext(context.Background()))
+
+	columns, rows := readTestParquet(t, cfg.Output.File)
+	require.Equal(t, parquet.ConvertedType_LIST, columns["pluginGoals"].GetConvertedType())
+	require.Equal(t, 1, rows.Len())
+
+	var goals, prefix reflect.Value
+	for i, key := range data.ArtifactAddRecordKeys {
+		switch key {
+		case keys.PluginGoals:
+			goals = rows.Index(0).Field(i + 1)
+		case keys.PluginPrefix:
+			prefix = rows.Index(0).Field(i + 1)
+		}
+	}
+	require.Equal(t, []string{"generate", "verify"}, goals.Interface())
+	require.Equal(t, "example", prefix.Elem().String())
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"encoding/json"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+)
+
+// SchemaVersion - the version of the JSON Schema NDJSON output conforms to.
+// Incremented on any change to the fields emitted, or their types
+const SchemaVersion = 1
+
+// the fields NDJSON output adds to each Record's payload
+const (
+	SchemaVersionKey = "schemaVersion"
+	RecordTypeKey    = "recordType"
+)
+
+// the fields of each RecordType, in schema order
+var schemaRecordKeys = []struct {
+	kind data.RecordType
+	keys [][]keys.Record
+}{
+	{data.ArtifactAdd, [][]keys.Record{data.ArtifactAddRecordKeys, data.OSGIRecordKeys}},
+	{data.ArtifactRemove, [][]keys.Record{data.ArtifactRemoveRecordKeys}},
+	{data.Descriptor, [][]keys.Record{data.DescriptorRecordKeys}},
+	{data.AllGroups, [][]keys.Record{data.AllGroupsRecordKeys}},
+	{data.RootGroups, [][]keys.Record{data.RootGroupsRecordKeys}},
+}
+
+// schemaFieldTypes - the JSON Schema of each field not encoded as a string
+var schemaFieldTypes = map[keys.Record]map[string]interface{}{
+	keys.RecordModified: {"type": "string", "format": "date-time"},
+	keys.FileModified:   {"type": "string", "format": "date-time"},
+	keys.FileSize:       {"type": "integer"},
+	keys.HasSources:     {"type": "boolean"},
+	keys.HasJavadoc:     {"type": "boolean"},
+	keys.HasSignature:   {"type": "boolean"},
+	keys.Classnames:     {"type": "array", "items": map[string]interface{}{"type": "string"}},
+	keys.AllGroupsList:  {"type": "array", "items": map[string]interface{}{"type": "string"}},
+	keys.RootGroupsList: {"type": "array", "items": map[string]interface{}{"type": "string"}},
+}
+
+// Schema - the JSON Schema document describing each line of NDJSON output,
+// generated from the data package's Record key lists. Each line is one of
+// the RecordTypes, distinguished by its "recordType"
+func Schema() ([]byte, error) {
+	var variants []interface{}
+	for _, rt := range schemaRecordKeys {
+		name := data.RecordTypeNames[rt.kind]
+		properties := map[string]interface{}{
+			SchemaVersionKey: map[string]interface{}{"const": SchemaVersion},
+			RecordTypeKey:    map[string]interface{}{"const": name},
+		}
+		for _, keyList := range rt.keys {
+			for _, key := range keyList {
+				fieldType, ok := schemaFieldTypes[key]
+				if !ok {
+					fieldType = map[string]interface{}{"type": "string"}
+				}
+				properties[key] = fieldType
+			}
+		}
+
+		variants = append(variants, map[string]interface{}{
+			"title":      name,
+			"type":       "object",
+			"required":   []string{SchemaVersionKey, RecordTypeKey},
+			"properties": properties,
+		})
+	}
+
+	schema := map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "Maven index record",
+		"description": "One line of maven-index-reader-go NDJSON output. Fields absent from the index record are omitted.",
+		"oneOf":       variants,
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
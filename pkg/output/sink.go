@@ -0,0 +1,114 @@
+package output
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+
+	"github.com/pkg/errors"
+)
+
+// ChunkMeta - identifies the index chunk the Records a Sink receives
+// between BeginChunk and EndChunk were scanned from
+type ChunkMeta struct {
+	// the chunk's resource, as resolved by config.Index.ResolveTarget
+	Target string
+
+	// the chunk ID. For the full index, the ID of the last
+	// incremental chunk it incorporates
+	ChunkID int
+
+	// whether the chunk is the full index, rather than an incremental
+	Full bool
+
+	// the index and chain the chunk belongs to, when the run
+	// is checkpointed, so a Sink may record the checkpoint itself
+	IndexID string
+	ChainID string
+
+	// the chunk timestamp, read from its header. Only set for EndChunk
+	Timestamp time.Time
+}
+
+// Sink - contract for output destinations. A Sink is opened once, then
+// receives the Records of each index chunk between BeginChunk and EndChunk,
+// one chunk at a time. Once EndChunk returns, the chunk's Records are
+// applied, so a transactional Sink commits there. Flush makes everything
+// written so far durable, as before a checkpoint, and Close flushes and
+// releases the Sink. The Records of a chunk begun but never ended, as when
+// its scan fails, may be discarded once the next chunk begins or the Sink
+// closes. Records written outside any chunk are kept as of the next Flush
+type Sink interface {
+	Open(ctx context.Context) error
+	BeginChunk(meta ChunkMeta) error
+	Write(record data.Record) error
+	EndChunk(meta ChunkMeta) error
+	Flush() error
+	Close() error
+}
+
+// SinkFactory - constructs a Sink for the configuration
+type SinkFactory func(l *log.Logger, cfg config.Index) (Sink, error)
+
+var (
+	sinksMu sync.RWMutex
+	sinks   = map[string]SinkFactory{}
+)
+
+// built-in Sinks, named as in config.OutputFormats
+func init() {
+	Register("log", func(l *log.Logger, c config.Index) (Sink, error) { return NewLogger(l, c), nil })
+	Register("json", func(l *log.Logger, c config.Index) (Sink, error) { return NewJSON(l, c), nil })
+	Register("ndjson", func(l *log.Logger, c config.Index) (Sink, error) { return NewNDJSON(l, c), nil })
+	Register("csv", func(l *log.Logger, c config.Index) (Sink, error) { return NewCSV(l, c) })
+	Register("parquet", func(l *log.Logger, c config.Index) (Sink, error) { return NewParquet(l, c), nil })
+	Register("sqlite", func(l *log.Logger, c config.Index) (Sink, error) { return NewSQLite(l, c), nil })
+}
+
+// Register - make a Sink available by name, as to config.Output.Sink and
+// the --format flag. Panics if the name is empty or already registered
+func Register(name string, factory SinkFactory) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	if len(name) == 0 || factory == nil {
+		panic("Output: Register requires a name and a SinkFactory")
+	}
+	if _, dup := sinks[name]; dup {
+		panic("Output: Register called twice for sink " + name)
+	}
+	sinks[name] = factory
+}
+
+// SinkNames - the names of the registered Sinks, sorted
+func SinkNames() []string {
+	sinksMu.RLock()
+	defer sinksMu.RUnlock()
+
+	out := make([]string, 0, len(sinks))
+	for name := range sinks {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+
+	return out
+}
+
+// NewSink - construct the Sink registered under the configured output's name
+func NewSink(l *log.Logger, cfg config.Index) (Sink, error) {
+	name := cfg.Output.FormatName()
+
+	sinksMu.RLock()
+	factory, ok := sinks[name]
+	sinksMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("Output: no sink registered as %q", name)
+	}
+
+	return factory(l, cfg)
+}
@@ -0,0 +1,201 @@
+package output
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/checkpoint"
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink - notes each call made to it, in order
+type recordingSink struct {
+	calls []string
+}
+
+func (rs *recordingSink) Open(_ context.Context) error {
+	rs.calls = append(rs.calls, "open")
+	return nil
+}
+
+func (rs *recordingSink) BeginChunk(meta ChunkMeta) error {
+	rs.calls = append(rs.calls, fmt.Sprintf("begin %d", meta.ChunkID))
+	return nil
+}
+
+func (rs *recordingSink) Write(record data.Record) error {
+	rs.calls = append(rs.calls, "write "+data.RecordTypeNames[record.Type()])
+	return nil
+}
+
+func (rs *recordingSink) EndChunk(meta ChunkMeta) error {
+	rs.calls = append(rs.calls, fmt.Sprintf("end %d at %d", meta.ChunkID, meta.Timestamp.UnixMilli()))
+	return nil
+}
+
+func (rs *recordingSink) Flush() error {
+	rs.calls = append(rs.calls, "flush")
+	return nil
+}
+
+func (rs *recordingSink) Close() error {
+	rs.calls = append(rs.calls, "close")
+	return nil
+}
+
+// testChunk - a Chunk already scanned, with the records
+// given, ending in the error given, if any
+func testChunk(meta ChunkMeta, records []data.Record, err error) *Chunk {
+	chunk := NewChunk(meta)
+	for _, record := range records {
+		chunk.Records() <- record
+	}
+	chunk.Finish(time.UnixMilli(int64(meta.ChunkID)*1000), err)
+
+	return chunk
+}
+
+func TestSinkRegistry(t *testing.T) {
+	sink := &recordingSink{}
+	Register("test-recorder", func(_ *log.Logger, _ config.Index) (Sink, error) { return sink, nil })
+	defer func() {
+		sinksMu.Lock()
+		delete(sinks, "test-recorder")
+		sinksMu.Unlock()
+	}()
+	require.Contains(t, SinkNames(), "test-recorder")
+	require.Panics(t, func() {
+		Register("test-recorder", func(_ *log.Logger, _ config.Index) (Sink, error) { return sink, nil })
+	})
+
+	// built-in formats are registered under their config.OutputFormats names
+	for name := range config.OutputFormats {
+		require.Contains(t, SinkNames(), name)
+	}
+
+	resolved, err := NewSink(log.Default(), config.Index{Output: config.Output{Sink: "test-recorder"}})
+	require.NoError(t, err)
+	require.Equal(t, sink, resolved)
+
+	_, err = NewSink(log.Default(), config.Index{Output: config.Output{Sink: "unregistered"}})
+	require.Error(t, err)
+
+	// a Format writes to the Sink without chunk boundaries
+	records := make(chan data.Record, 1)
+	records <- testCSVRecords(t)[1]
+	close(records)
+	require.NoError(t, ResolveFormat(log.Default(), records, config.Index{Output: config.Output{Sink: "test-recorder"}}).Write())
+	require.Equal(t, []string{"open", "write artifact_remove", "close"}, sink.calls)
+}
+
+func TestDrive(t *testing.T) {
+	records := testCSVRecords(t)
+	sink := &recordingSink{}
+	scanErr := errors.New("chunk expired")
+
+	chunks := make(chan *Chunk, 3)
+	first := testChunk(ChunkMeta{ChunkID: 5}, records[1:2], nil)
+	failed := testChunk(ChunkMeta{ChunkID: 6}, records[2:3], scanErr)
+	last := testChunk(ChunkMeta{ChunkID: 7}, records[2:3], nil)
+	chunks <- first
+	chunks <- failed
+	chunks <- last
+	close(chunks)
+
+	flushes := make(chan FlushRequest)
+	require.NoError(t, Drive(context.Background(), sink, chunks, flushes))
+
+	// the failed chunk is never ended, and the next follows
+	require.NoError(t, <-first.Ended())
+	require.Equal(t, scanErr, <-failed.Ended())
+	require.NoError(t, <-last.Ended())
+	require.Equal(t, []string{
+		"open",
+		"begin 5", "write artifact_remove", "end 5 at 5000",
+		"begin 6", "write artifact_add",
+		"begin 7", "write artifact_add", "end 7 at 7000",
+		"close",
+	}, sink.calls)
+
+	// a cancelled drive abandons the chunk in progress
+	sink = &recordingSink{}
+	chunks = make(chan *Chunk, 1)
+	pending := NewChunk(ChunkMeta{ChunkID: 8})
+	chunks <- pending
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		pending.Records() <- records[1]
+		cancel()
+	}()
+	require.ErrorIs(t, Drive(ctx, sink, chunks, nil), context.Canceled)
+	require.ErrorIs(t, <-pending.Ended(), context.Canceled)
+	require.NotContains(t, sink.calls, "end 8 at 0")
+	require.Equal(t, "close", sink.calls[len(sink.calls)-1])
+}
+
+func TestSQLiteChunks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "central.db")
+	add := func(uinfo string) data.Record {
+		record, err := data.NewRecord(log.Default(), testSQLiteAdd(uinfo, "1000"))
+		require.NoError(t, err)
+		return record
+	}
+
+	// the full index replaces the artifact set, and a chunk whose scan
+	// fails is rolled back, rather than partially applied
+	writeTestSQLite(t, path,
+		testSQLiteAdd("org.example|stale|1.0|NA|jar", "1000"),
+		map[string]string{keys.AllGroups: "All Groups", string(keys.AllGroupsList): "org.stale"},
+	)
+	chunks := make(chan *Chunk, 3)
+	meta := ChunkMeta{IndexID: "central", ChainID: "chain"}
+	meta.ChunkID, meta.Full = 5, true
+	chunks <- testChunk(meta, []data.Record{add("org.example|widget|1.0|NA|jar")}, nil)
+	meta.ChunkID, meta.Full = 6, false
+	chunks <- testChunk(meta, []data.Record{add("org.example|gadget|1.0|NA|jar")}, errors.New("chunk expired"))
+	meta.ChunkID = 7
+	chunks <- testChunk(meta, []data.Record{add("org.example|gizmo|1.0|NA|jar")}, nil)
+	close(chunks)
+
+	cfg := config.Index{Output: config.Output{Format: config.SQLite, File: path}}
+	sink, err := NewSink(log.Default(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, Drive(context.Background(), sink, chunks, nil))
+
+	db, err := sql.Open("sqlite3", SQLiteDSN(path))
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT artifact_id FROM artifacts ORDER BY artifact_id`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var artifacts []string
+	for rows.Next() {
+		var artifactID string
+		require.NoError(t, rows.Scan(&artifactID))
+		artifacts = append(artifacts, artifactID)
+	}
+	require.Equal(t, []string{"gizmo", "widget"}, artifacts)
+
+	var groups string
+	require.NoError(t, db.QueryRow(`SELECT group_concat(group_id) FROM groups`).Scan(&groups))
+	require.Equal(t, "org.example", groups, "the full index clears stale groups")
+
+	// each ended chunk's checkpoint is committed with its records
+	latest, ok, err := checkpoint.NewSQLiteStore(log.Default(), path).Load("central", "chain")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 7, latest.ChunkID)
+	require.Equal(t, int64(7000), latest.Timestamp.UnixMilli())
+}
@@ -0,0 +1,405 @@
+package output
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/checkpoint"
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// the SQLite schema, created if absent. Artifacts are keyed by coordinates,
+// with an empty rather than null classifier or extension when absent. A
+// removal leaves a tombstone, so that an older add scanned from another
+// chunk afterwards doesn't resurrect the artifact. Timestamps are Unix millis
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS artifacts (
+		group_id        TEXT NOT NULL,
+		artifact_id     TEXT NOT NULL,
+		version         TEXT NOT NULL,
+		classifier      TEXT NOT NULL DEFAULT '',
+		extension       TEXT NOT NULL DEFAULT '',
+		packaging       TEXT,
+		record_modified INTEGER NOT NULL DEFAULT 0,
+		file_modified   INTEGER,
+		file_size       INTEGER,
+		has_sources     INTEGER,
+		has_javadoc     INTEGER,
+		has_signature   INTEGER,
+		name            TEXT,
+		description     TEXT,
+		sha1            TEXT,
+		plugin_prefix   TEXT,
+		plugin_goals    TEXT,
+		PRIMARY KEY (group_id, artifact_id, version, classifier, extension)
+	)`,
+	`CREATE INDEX IF NOT EXISTS artifacts_sha1 ON artifacts (sha1)`,
+	`CREATE INDEX IF NOT EXISTS artifacts_group_id ON artifacts (group_id)`,
+	`CREATE TABLE IF NOT EXISTS classnames (
+		group_id    TEXT NOT NULL,
+		artifact_id TEXT NOT NULL,
+		version     TEXT NOT NULL,
+		classifier  TEXT NOT NULL DEFAULT '',
+		extension   TEXT NOT NULL DEFAULT '',
+		classname   TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS classnames_artifact ON classnames (group_id, artifact_id, version, classifier, extension)`,
+	`CREATE INDEX IF NOT EXISTS classnames_classname ON classnames (classname)`,
+	`CREATE TABLE IF NOT EXISTS groups (
+		group_id TEXT NOT NULL PRIMARY KEY,
+		root     INTEGER NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS removals (
+		group_id        TEXT NOT NULL,
+		artifact_id     TEXT NOT NULL,
+		version         TEXT NOT NULL,
+		classifier      TEXT NOT NULL DEFAULT '',
+		extension       TEXT NOT NULL DEFAULT '',
+		record_modified INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (group_id, artifact_id, version, classifier, extension)
+	)`,
+	checkpoint.SQLiteMetadataSchema,
+}
+
+const (
+	// an add applies unless the artifact is already at a newer
+	// record, or was removed by one
+	sqliteUpsertArtifact = `INSERT INTO artifacts (
+		group_id, artifact_id, version, classifier, extension, packaging, record_modified,
+		file_modified, file_size, has_sources, has_javadoc, has_signature,
+		name, description, sha1, plugin_prefix, plugin_goals
+	)
+	SELECT ?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16, ?17
+	WHERE NOT EXISTS (
+		SELECT 1 FROM removals
+		WHERE group_id = ?1 AND artifact_id = ?2 AND version = ?3 AND classifier = ?4 AND extension = ?5
+		AND record_modified > ?7
+	)
+	ON CONFLICT (group_id, artifact_id, version, classifier, extension) DO UPDATE SET
+		packaging = excluded.packaging,
+		record_modified = excluded.record_modified,
+		file_modified = excluded.file_modified,
+		file_size = excluded.file_size,
+		has_sources = excluded.has_sources,
+		has_javadoc = excluded.has_javadoc,
+		has_signature = excluded.has_signature,
+		name = excluded.name,
+		description = excluded.description,
+		sha1 = excluded.sha1,
+		plugin_prefix = excluded.plugin_prefix,
+		plugin_goals = excluded.plugin_goals
+	WHERE excluded.record_modified >= artifacts.record_modified`
+
+	sqliteRecordRemoval = `INSERT INTO removals (group_id, artifact_id, version, classifier, extension, record_modified)
+	VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+	ON CONFLICT (group_id, artifact_id, version, classifier, extension) DO UPDATE SET
+		record_modified = excluded.record_modified
+	WHERE excluded.record_modified > removals.record_modified`
+
+	sqliteDeleteArtifact = `DELETE FROM artifacts
+	WHERE group_id = ?1 AND artifact_id = ?2 AND version = ?3 AND classifier = ?4 AND extension = ?5
+	AND record_modified <= ?6`
+
+	sqliteDeleteClassnames = `DELETE FROM classnames
+	WHERE group_id = ?1 AND artifact_id = ?2 AND version = ?3 AND classifier = ?4 AND extension = ?5`
+
+	sqliteInsertClassname = `INSERT INTO classnames (group_id, artifact_id, version, classifier, extension, classname)
+	VALUES (?1, ?2, ?3, ?4, ?5, ?6)`
+
+	sqliteInsertGroup = `INSERT INTO groups (group_id, root) VALUES (?1, ?2)
+	ON CONFLICT (group_id) DO UPDATE SET root = MAX(root, excluded.root)`
+)
+
+// SQLiteDSN - the database/sql data source name of the SQLite database at path
+func SQLiteDSN(path string) string {
+	return fmt.Sprintf("file:%s?_busy_timeout=10000&_journal_mode=WAL", path)
+}
+
+// SQLite - maintains a SQLite database of the current artifact set in
+// Output.File. ArtifactAdd Records are upserted, ArtifactRemove Records
+// deleted, and groups from any Record type are collected. Each chunk is
+// applied in a transaction committed by EndChunk, and the full index
+// replaces the artifact set. Records outside a chunk are committed on Flush
+type SQLite struct {
+	logger *log.Logger
+	cfg    config.Index
+
+	db      *sql.DB
+	batch   *sqliteBatch
+	inChunk bool
+	count   int
+}
+
+func NewSQLite(l *log.Logger, c config.Index) *SQLite {
+	l.Printf("Output: applying data.Records to SQLite database...\n")
+	return &SQLite{logger: l, cfg: c}
+}
+
+func (s *SQLite) Open(ctx context.Context) error {
+	path := s.cfg.Output.File
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrapf(err, "SQLite: failed to create output directory at %s with cause", filepath.Dir(path))
+	}
+
+	db, err := sql.Open("sqlite3", SQLiteDSN(path))
+	if err != nil {
+		return errors.Wrapf(err, "SQLite: failed to open database %s with cause", path)
+	}
+
+	for _, stmt := range sqliteSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			db.Close()
+			return errors.Wrapf(err, "SQLite: failed to create schema in database %s with cause", path)
+		}
+	}
+
+	s.db = db
+	return nil
+}
+
+// BeginChunk - discards any chunk begun but never ended
+func (s *SQLite) BeginChunk(meta ChunkMeta) error {
+	if s.inChunk {
+		s.logger.Printf("SQLite: discarding records of unfinished chunk from database %s", s.cfg.Output.File)
+		s.batch.rollback()
+		s.batch = nil
+	}
+	if err := s.Flush(); err != nil {
+		return err
+	}
+
+	batch, err := beginSQLiteBatch(s.db, s.cfg.Output.File)
+	if err != nil {
+		return err
+	}
+	s.batch, s.inChunk = batch, true
+
+	if meta.Full {
+		for _, table := range []string{"artifacts", "classnames", "removals", "groups"} {
+			if _, err := s.batch.exec("DELETE FROM " + table); err != nil {
+				return errors.Wrapf(err, "SQLite: failed to clear table %s for the full index with cause", table)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLite) Write(record data.Record) error {
+	if s.batch == nil {
+		batch, err := beginSQLiteBatch(s.db, s.cfg.Output.File)
+		if err != nil {
+			return err
+		}
+		s.batch = batch
+	}
+
+	if err := s.batch.apply(record); err != nil {
+		return errors.Wrapf(err, "SQLite: failed to apply Record %d to database %s with cause", s.count+1, s.cfg.Output.File)
+	}
+	s.count++
+
+	return nil
+}
+
+// EndChunk - commits the chunk's Records, and when the run is checkpointed,
+// the chunk's checkpoint with them, so a chunk is never applied unrecorded
+func (s *SQLite) EndChunk(meta ChunkMeta) error {
+	if meta.IndexID != "" {
+		c := checkpoint.Checkpoint{IndexID: meta.IndexID, ChainID: meta.ChainID, ChunkID: meta.ChunkID, Timestamp: meta.Timestamp}
+		if err := checkpoint.SaveSQLiteTx(s.batch.tx, c); err != nil {
+			return errors.Wrapf(err, "SQLite: failed to write checkpoint to database %s with cause", s.cfg.Output.File)
+		}
+	}
+
+	s.inChunk = false
+	return s.Flush()
+}
+
+// Flush - commits Records written outside a chunk. A chunk's
+// Records are only committed together, by EndChunk
+func (s *SQLite) Flush() error {
+	if s.batch == nil || s.inChunk {
+		return nil
+	}
+
+	err := s.batch.commit()
+	s.batch = nil
+	return err
+}
+
+func (s *SQLite) Close() error {
+	defer s.db.Close()
+
+	if s.inChunk {
+		s.batch.rollback()
+		s.batch, s.inChunk = nil, false
+	}
+	if err := s.Flush(); err != nil {
+		return err
+	}
+
+	s.logger.Printf("SQLite: applied %d records to database %s", s.count, s.cfg.Output.File)
+	return nil
+}
+
+// sqliteBatch - a transaction, and the statements prepared in it
+type sqliteBatch struct {
+	path  string
+	tx    *sql.Tx
+	stmts map[string]*sql.Stmt
+}
+
+func beginSQLiteBatch(db *sql.DB, path string) (*sqliteBatch, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.Wrapf(err, "SQLite: failed to begin transaction in database %s with cause", path)
+	}
+	return &sqliteBatch{path: path, tx: tx, stmts: map[string]*sql.Stmt{}}, nil
+}
+
+// exec - run the query, prepared on first use in the transaction
+func (b *sqliteBatch) exec(query string, args ...interface{}) (sql.Result, error) {
+	stmt, ok := b.stmts[query]
+	if !ok {
+		var err error
+		if stmt, err = b.tx.Prepare(query); err != nil {
+			return nil, err
+		}
+		b.stmts[query] = stmt
+	}
+	return stmt.Exec(args...)
+}
+
+func (b *sqliteBatch) commit() error {
+	if err := b.tx.Commit(); err != nil {
+		return errors.Wrapf(err, "SQLite: failed to commit to database %s with cause", b.path)
+	}
+	return nil
+}
+
+func (b *sqliteBatch) rollback() {
+	b.tx.Rollback()
+}
+
+// apply - upsert, delete, or collect the groups of the Record
+func (b *sqliteBatch) apply(record data.Record) error {
+	switch record.Type() {
+	case data.ArtifactAdd:
+		coords := sqliteCoordinates(record)
+		res, err := b.exec(sqliteUpsertArtifact, append(coords,
+			sqliteValue(record.Get(keys.Packaging)),
+			sqliteMillis(record.Get(keys.RecordModified)),
+			sqliteValue(record.Get(keys.FileModified)),
+			sqliteValue(record.Get(keys.FileSize)),
+			sqliteValue(record.Get(keys.HasSources)),
+			sqliteValue(record.Get(keys.HasJavadoc)),
+			sqliteValue(record.Get(keys.HasSignature)),
+			sqliteValue(record.Get(keys.Name)),
+			sqliteValue(record.Get(keys.Description)),
+			sqliteValue(record.Get(keys.SHA1)),
+			sqliteValue(record.Get(keys.PluginPrefix)),
+			sqliteValue(record.Get(keys.PluginGoals)),
+		)...)
+		if err != nil {
+			return err
+		}
+
+		// superseded adds leave the stored class names alone
+		if applied, err := res.RowsAffected(); err != nil || applied == 0 {
+			return err
+		}
+		if _, err := b.exec(sqliteDeleteClassnames, coords...); err != nil {
+			return err
+		}
+		classnames, _ := record.Get(keys.Classnames).([]string)
+		for _, classname := range classnames {
+			if _, err := b.exec(sqliteInsertClassname, append(coords, classname)...); err != nil {
+				return err
+			}
+		}
+
+		_, err = b.exec(sqliteInsertGroup, coords[0], false)
+		return err
+
+	case data.ArtifactRemove:
+		coords := sqliteCoordinates(record)
+		modified := sqliteMillis(record.Get(keys.RecordModified))
+		if _, err := b.exec(sqliteRecordRemoval, append(coords, modified)...); err != nil {
+			return err
+		}
+
+		res, err := b.exec(sqliteDeleteArtifact, append(coords, modified)...)
+		if err != nil {
+			return err
+		}
+		if deleted, err := res.RowsAffected(); err != nil || deleted == 0 {
+			return err
+		}
+		_, err = b.exec(sqliteDeleteClassnames, coords...)
+		return err
+
+	case data.AllGroups, data.RootGroups:
+		listKey, root := keys.AllGroupsList, false
+		if record.Type() == data.RootGroups {
+			listKey, root = keys.RootGroupsList, true
+		}
+
+		groups, _ := record.Get(listKey).([]string)
+		for _, group := range groups {
+			if _, err := b.exec(sqliteInsertGroup, group, root); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sqliteCoordinates - the artifacts key of the Record, absent values empty.
+// Without a classifier, the extension in an ArtifactRemove's UINFO is parsed
+// as its packaging, so that stands in for the extension
+func sqliteCoordinates(record data.Record) []interface{} {
+	out := []interface{}{}
+	for _, key := range []keys.Record{keys.GroupID, keys.ArtifactID, keys.Version, keys.Classifier} {
+		value, _ := record.Get(key).(string)
+		out = append(out, value)
+	}
+
+	extension, ok := record.Get(keys.FileExtension).(string)
+	if !ok {
+		extension, _ = record.Get(keys.Packaging).(string)
+	}
+	return append(out, extension)
+}
+
+// sqliteMillis - the Record timestamp in Unix millis, or 0 if absent
+func sqliteMillis(value interface{}) int64 {
+	if ts, ok := value.(time.Time); ok {
+		return ts.UnixMilli()
+	}
+	return 0
+}
+
+// sqliteValue - encode a Record value as a column value, null if absent
+func sqliteValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UnixMilli()
+	case []string:
+		return strings.Join(v, data.RecordValueSeparator)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,106 @@
+package output
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testSQLiteAdd(uinfo, modified string) map[string]string {
+	return map[string]string{
+		data.UInfoKey:          uinfo,
+		data.InfoKey:           "jar|1243533415343|1024|1|0|1|jar",
+		data.RecordModifiedKey: modified,
+		data.ClassnamesKey:     "/org/example/Widget|/org/example/Gadget",
+		data.SHA1Key:           "38bb5a445e9aa5a38581743ede58f46c0f1ce321",
+	}
+}
+
+func writeTestSQLite(t *testing.T, path string, raws ...map[string]string) {
+	records := make(chan data.Record, len(raws))
+	for _, raw := range raws {
+		record, err := data.NewRecord(log.Default(), raw)
+		require.NoError(t, err)
+		records <- record
+	}
+	close(records)
+
+	cfg := config.Index{Output: config.Output{Format: config.SQLite, File: path}}
+	require.NoError(t, ResolveFormat(log.Default(), records, cfg).WriteContext(context.Background()))
+}
+
+func TestSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db", "central.db")
+
+	// records of chunks scanned in parallel arrive in any order
+	writeTestSQLite(t, path,
+		map[string]string{keys.AllGroups: keys.AllGroups, keys.AllGroupsList: "org.example|com.acme"},
+		map[string]string{keys.RootGroups: keys.RootGroups, keys.RootGroupsList: "org|com"},
+		testSQLiteAdd("org.example|widget|1.0|NA|jar", "1000"),
+		testSQLiteAdd("org.example|gadget|2.0|NA|jar", "1000"),
+		testSQLiteAdd("org.example|gizmo|3.0|NA|jar", "1000"),
+		// a newer removal deletes, and a stale one is ignored
+		map[string]string{keys.Del: "org.example|widget|1.0|NA|jar", data.RecordModifiedKey: "2000"},
+		map[string]string{keys.Del: "org.example|gadget|2.0|NA|jar", data.RecordModifiedKey: "500"},
+		// an add older than a removal doesn't resurrect the artifact
+		map[string]string{keys.Del: "org.example|gizmo|3.0|NA|jar", data.RecordModifiedKey: "2000"},
+		testSQLiteAdd("org.example|gizmo|3.0|NA|jar", "1500"),
+	)
+
+	db, err := sql.Open("sqlite3", SQLiteDSN(path))
+	require.NoError(t, err)
+	defer db.Close()
+
+	artifacts := func() []string {
+		rows, err := db.Query(`SELECT artifact_id FROM artifacts ORDER BY artifact_id`)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var out []string
+		for rows.Next() {
+			var artifactID string
+			require.NoError(t, rows.Scan(&artifactID))
+			out = append(out, artifactID)
+		}
+		return out
+	}
+	require.Equal(t, []string{"gadget"}, artifacts())
+
+	var extension, sha1 string
+	var modified, fileSize int64
+	var hasSources, hasJavadoc bool
+	require.NoError(t, db.QueryRow(`SELECT extension, record_modified, file_size, has_sources, has_javadoc, sha1
+		FROM artifacts WHERE artifact_id = 'gadget'`).Scan(&extension, &modified, &fileSize, &hasSources, &hasJavadoc, &sha1))
+	require.Equal(t, "jar", extension)
+	require.Equal(t, int64(1000), modified)
+	require.Equal(t, int64(1024), fileSize)
+	require.True(t, hasSources)
+	require.False(t, hasJavadoc)
+	require.Equal(t, "38bb5a445e9aa5a38581743ede58f46c0f1ce321", sha1)
+
+	var classnames int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM classnames`).Scan(&classnames))
+	require.Equal(t, 2, classnames)
+
+	var groups, roots int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*), SUM(root) FROM groups`).Scan(&groups, &roots))
+	require.Equal(t, 4, groups)
+	require.Equal(t, 2, roots)
+
+	// a later incremental run re-adds, and updates in place
+	writeTestSQLite(t, path,
+		testSQLiteAdd("org.example|widget|1.0|NA|jar", "3000"),
+		testSQLiteAdd("org.example|gadget|2.0|NA|jar", "3000"),
+	)
+	require.Equal(t, []string{"gadget", "widget"}, artifacts())
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM classnames`).Scan(&classnames))
+	require.Equal(t, 4, classnames)
+}
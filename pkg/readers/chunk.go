@@ -1,7 +1,9 @@
 package readers
 
 import (
+	"bufio"
 	"compress/gzip"
+	"context"
 	"io"
 	"log"
 	"time"
@@ -38,96 +40,127 @@ func NewChunk(l *log.Logger, b chan<- data.Record, c config.Index, t string, ff
 	}
 }
 
-// Read - initiate async consumption of Resource and population of data.Record buffer
+// Read - consume the Resource and populate the data.Record buffer. As the
+// Chunk is read to completion, the error returned on success wraps io.EOF.
+// Callers who prefer to pull records synchronously should use OpenChunk
 func (cr Chunk) Read() error {
-	resource, err := resources.FromConfig(cr.logger, cr.cfg, cr.target)
-	defer resource.Close()
+	return cr.ReadContext(context.Background())
+}
 
-	rdr, err := resource.Reader()
-	if err != nil {
-		return errors.Wrapf(err, "Chunk: failed to obtain data stream from %s with cause", resource)
+// ReadContext - as Read, but stops with the context's error once it is done
+func (cr Chunk) ReadContext(ctx context.Context) error {
+	it := OpenChunkContext(ctx, cr.logger, cr.cfg, cr.target, cr.filterFn)
+	defer it.Close()
+
+	for it.Next() {
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "Chunk(%s): cancelled after %d records with cause", cr.target, it.count-1)
+		case cr.buffer <- it.Record():
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
 	}
 
-	gzRdr, err := gzip.NewReader(rdr)
-	if err != nil {
-		return errors.Wrapf(err, "Chunk: failed to wrap %s in GZIP Reader with cause", resource)
+	cr.logger.Printf("Chunk: successfully published %d records from %s", it.count, cr.target)
+	return errors.Wrapf(io.EOF, "Chunk(%s): read to completion", cr.target)
+}
+
+// ChunkIterator - pulls data.Records from an index chunk one at a time:
+//
+//	it := readers.OpenChunk(logger, cfg, target, filterFn)
+//	defer it.Close()
+//	for it.Next() {
+//		record := it.Record()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ChunkIterator struct {
+	ctx      context.Context
+	target   string
+	cfg      config.Index
+	logger   *log.Logger
+	filterFn FilterFunc
+
+	resource resources.Resource
+	gzRdr    *gzip.Reader
+	rdr      *bufio.Reader
+
+	version   uint8
+	timestamp time.Time
+	record    data.Record
+	count     int
+	done      bool
+	err       error
+}
+
+// OpenChunk - resolve an iterator over the data.Records in the target chunk
+// that pass the optional filter. The underlying Resource is opened on the
+// first call to Next, and released at end of stream, on error, or on Close
+func OpenChunk(l *log.Logger, c config.Index, t string, ff FilterFunc) *ChunkIterator {
+	return OpenChunkContext(context.Background(), l, c, t, ff)
+}
+
+// OpenChunkContext - as OpenChunk, but the iterator stops with the
+// context's error, and releases the Resource, once the context is done
+func OpenChunkContext(ctx context.Context, l *log.Logger, c config.Index, t string, ff FilterFunc) *ChunkIterator {
+	return &ChunkIterator{
+		ctx:      ctx,
+		target:   t,
+		cfg:      c,
+		logger:   l,
+		filterFn: ff,
 	}
-	defer gzRdr.Close()
+}
 
-	var chunkVersion uint8
-	if b, err := utils.ReadByte(gzRdr); err == nil {
-		chunkVersion = uint8(b)
-	} else {
-		return errors.Wrapf(err, "Chunk(%s): failed to read chunk version with cause", cr.target)
+// Next - advance to the next data.Record, returning false
+// at the end of the chunk or if an error was encountered
+func (it *ChunkIterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
 	}
 
-	var chunkTimestamp time.Time
-	if i64, err := utils.ReadInt64(gzRdr); err == nil {
-		secs := i64 / 1000
-		nanos := (i64 % 1000) * 1000000
-		chunkTimestamp = time.Unix(secs, nanos)
-	} else {
-		return errors.Wrapf(err, "Chunk(%s): failed to read chunk timestamp with cause", cr.target)
+	if it.rdr == nil {
+		if err := it.open(); err != nil {
+			it.fail(err)
+			return false
+		}
 	}
-	cr.logger.Printf("Chunk(%s): version %d at time %s", cr.target, chunkVersion, chunkTimestamp)
 
-	count := 1
 	for {
-		var fieldCount int32
-		fieldCount, err = utils.ReadInt32(gzRdr)
-		if err != nil {
-			return errors.Wrapf(err,
-				"Chunk(%s): failed to read field count for record %d with cause",
-				cr.target, count)
+		if err := it.ctx.Err(); err != nil {
+			it.fail(errors.Wrapf(err, "Chunk(%s): cancelled after %d records with cause", it.target, it.count))
+			return false
 		}
 
-		rawRecord := map[string]string{}
-		for ndx := int32(0); ndx < fieldCount; ndx++ {
-			// we ignore each Record's 1 byte of index bit flags
-			_, err = utils.ReadByte(gzRdr)
-			if err != nil {
-				return errors.Wrapf(err,
-					"Chunk(%s): failed to read field flags for record %d with cause",
-					cr.target, count)
-			}
-
-			// a Record's *key* conforms to standard Java "readUTF" behavior
-			// including a max size field of 2 bytes
-			key, err := utils.ReadString(gzRdr)
-			if err != nil {
-				return errors.Wrapf(err,
-					"Chunk(%s): failed to read field key for record %d with cause",
-					cr.target, count)
-			}
-
-			// a Record's *value* can be larger; the size field is 4 bytes
-			// https://github.com/apache/maven-indexer/blob/31052fdeebc8a9f845eb18cd4c13669b316b3e29/indexer-reader/src/main/java/org/apache/maven/index/reader/Chunk.java#L189
-			// https://github.com/apache/maven-indexer/blob/31052fdeebc8a9f845eb18cd4c13669b316b3e29/indexer-reader/src/main/java/org/apache/maven/index/reader/Chunk.java#L196
-			value, err := utils.ReadLargeString(gzRdr)
-			if err != nil && errors.Cause(err) != io.EOF {
-				// why io.EOF check here? it's quite possible for a
-				// well-formed *value* to be returned here with an EOF,
-				// indicating we have obtained one last well-formed KV
-				// pair to add to this (now complete) Record
-				return errors.Wrapf(err,
-					"Chunk(%s): failed to read field value for key %s on record %d with cause",
-					cr.target, key, count)
-			}
+		// a clean end of stream falls between records
+		if _, err := it.rdr.Peek(1); err == io.EOF {
+			it.done = true
+			it.release()
+			return false
+		} else if err != nil {
+			// like a failed content verification at end of stream
+			it.fail(errors.Wrapf(err, "Chunk(%s): failed to read record %d with cause", it.target, it.count+1))
+			return false
+		}
 
-			rawRecord[key] = value
+		rawRecord, err := it.readRawRecord()
+		if err != nil {
+			it.fail(err)
+			return false
 		}
 
 		// parse raw captured KVs into a Record
-		record, rErr := data.NewRecord(cr.logger, rawRecord)
+		record, rErr := data.NewRecordFromFields(it.logger, rawRecord)
 
 		// before we care about Record parsing errors, let's
 		// make sure the caller wants this Record at all
-		if cr.filterFn != nil && !cr.filterFn(record) {
-			if cr.cfg.Verbose {
-				cr.logger.Printf("Chunk(%s): skipping filtered record: %+v", cr.target, record)
-			}
-			if errors.Cause(err) == io.EOF {
-				return nil
+		if it.filterFn != nil && !it.filterFn(record) {
+			if it.cfg.Verbose {
+				it.logger.Printf("Chunk(%s): skipping filtered record: %+v", it.target, record)
 			}
 			continue
 		}
@@ -135,18 +168,150 @@ func (cr Chunk) Read() error {
 		// OK, before we pass the new Record along for post-processing,
 		// let's make sure it isn't corrupted
 		if rErr != nil {
-			return errors.Wrapf(err,
-				"Chunk(%s): failed to compose well-formed record %d from %s with cause: %s",
-				cr.target, count, rawRecord, rErr)
+			it.fail(errors.Wrapf(rErr,
+				"Chunk(%s): failed to compose well-formed record %d from %+v with cause",
+				it.target, it.count+1, rawRecord))
+			return false
 		}
 
-		cr.buffer <- record
+		it.record = record
+		it.count++
+		return true
+	}
+}
+
+// Record - the data.Record resolved by the latest call to Next
+func (it *ChunkIterator) Record() data.Record {
+	return it.record
+}
 
-		// if this was the last well-formed Record in the index Chunk, we're done
-		if errors.Cause(err) == io.EOF {
-			cr.logger.Printf("Chunk: successfully published %d records from %s", count, resource)
-			return nil
+// Err - the first error encountered by the iterator, if any.
+// Reaching the end of the chunk is not an error
+func (it *ChunkIterator) Err() error {
+	return it.err
+}
+
+// Version - the chunk format version, available after the first call to Next
+func (it *ChunkIterator) Version() uint8 {
+	return it.version
+}
+
+// Timestamp - the chunk timestamp, available after the first call to Next
+func (it *ChunkIterator) Timestamp() time.Time {
+	return it.timestamp
+}
+
+// Close - release the underlying Resource. Safe to call
+// repeatedly, and after the iterator is exhausted
+func (it *ChunkIterator) Close() error {
+	it.done = true
+	return it.release()
+}
+
+func (it *ChunkIterator) open() error {
+	resource, err := resources.FromConfig(it.logger, it.cfg, it.target)
+	if err != nil {
+		return errors.Wrapf(err, "Chunk: failed to resolve resource %s with cause", it.target)
+	}
+	it.resource = resource
+
+	rdr, err := resource.ReaderContext(it.ctx)
+	if err != nil {
+		return errors.Wrapf(err, "Chunk: failed to obtain data stream from %s with cause", resource)
+	}
+
+	gzRdr, err := gzip.NewReader(utils.NewContextReader(it.ctx, rdr))
+	if err != nil {
+		return errors.Wrapf(err, "Chunk: failed to wrap %s in GZIP Reader with cause", resource)
+	}
+	it.gzRdr = gzRdr
+	it.rdr = bufio.NewReader(utils.NewContextReader(it.ctx, gzRdr))
+
+	if b, err := utils.ReadByte(it.rdr); err == nil {
+		it.version = uint8(b)
+	} else {
+		return errors.Wrapf(err, "Chunk(%s): failed to read chunk version with cause", it.target)
+	}
+
+	if i64, err := utils.ReadInt64(it.rdr); err == nil {
+		it.timestamp = time.UnixMilli(i64)
+	} else {
+		return errors.Wrapf(err, "Chunk(%s): failed to read chunk timestamp with cause", it.target)
+	}
+	it.logger.Printf("Chunk(%s): version %d at time %s", it.target, it.version, it.timestamp)
+
+	return nil
+}
+
+func (it *ChunkIterator) readRawRecord() ([]data.RawField, error) {
+	fieldCount, err := utils.ReadInt32(it.rdr)
+	if err != nil {
+		return nil, errors.Wrapf(unexpectedEOF(err),
+			"Chunk(%s): failed to read field count for record %d with cause",
+			it.target, it.count+1)
+	}
+
+	rawRecord := make([]data.RawField, 0, fieldCount)
+	for ndx := int32(0); ndx < fieldCount; ndx++ {
+		// each field is preceded by 1 byte of index bit flags
+		flags, err := utils.ReadByte(it.rdr)
+		if err != nil {
+			return nil, errors.Wrapf(unexpectedEOF(err),
+				"Chunk(%s): failed to read field flags for record %d with cause",
+				it.target, it.count+1)
+		}
+
+		// a Record's *key* conforms to standard Java "readUTF" behavior
+		// including a max size field of 2 bytes
+		key, err := utils.ReadString(it.rdr)
+		if err != nil {
+			return nil, errors.Wrapf(unexpectedEOF(err),
+				"Chunk(%s): failed to read field key for record %d with cause",
+				it.target, it.count+1)
+		}
+
+		// a Record's *value* can be larger; the size field is 4 bytes
+		// https://github.com/apache/maven-indexer/blob/31052fdeebc8a9f845eb18cd4c13669b316b3e29/indexer-reader/src/main/java/org/apache/maven/index/reader/Chunk.java#L189
+		// https://github.com/apache/maven-indexer/blob/31052fdeebc8a9f845eb18cd4c13669b316b3e29/indexer-reader/src/main/java/org/apache/maven/index/reader/Chunk.java#L196
+		value, err := utils.ReadLargeString(it.rdr)
+		if err != nil {
+			return nil, errors.Wrapf(unexpectedEOF(err),
+				"Chunk(%s): failed to read field value for key %s on record %d with cause",
+				it.target, key, it.count+1)
+		}
+
+		rawRecord = append(rawRecord, data.RawField{Key: key, Value: value, Flags: data.FieldFlags(flags)})
+	}
+
+	return rawRecord, nil
+}
+
+// a clean end of stream falls between records, so
+// any io.EOF within one means the record was truncated
+func unexpectedEOF(err error) error {
+	if errors.Cause(err) == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (it *ChunkIterator) fail(err error) {
+	it.err = err
+	it.release()
+}
+
+func (it *ChunkIterator) release() error {
+	var err error
+	if it.gzRdr != nil {
+		err = it.gzRdr.Close()
+		it.gzRdr = nil
+	}
+	if it.resource != nil {
+		if rErr := it.resource.Close(); rErr != nil && err == nil {
+			err = rErr
 		}
-		count++
+		it.resource = nil
 	}
+
+	return err
 }
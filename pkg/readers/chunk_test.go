@@ -1,13 +1,21 @@
 package readers
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"io"
 	"log"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/elireisman/maven-index-reader-go/pkg/config"
 	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/resources"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
@@ -127,3 +135,192 @@ func TestFilteredChunk(t *testing.T) {
 
 	close(records)
 }
+
+func TestChunkIterator(t *testing.T) {
+	logger := log.Default()
+
+	simpleCfg := config.Index{
+		Meta: config.Meta{
+			ID:      "apache-snapshots-local",
+			ChainID: "1243533418968",
+			File:    "nexus-maven-repository-index",
+		},
+		Source: config.Source{
+			Base: "testdata/",
+			Type: config.Local,
+		},
+		Mode: config.Mode{
+			Type: config.All,
+		},
+		Output: config.Output{
+			Format: config.Log,
+		},
+	}
+	require.NoError(t, config.Validate(logger, simpleCfg))
+
+	target := simpleCfg.ResolveTarget(".gz")
+
+	it := OpenChunk(logger, simpleCfg, target, nil)
+	var types []data.RecordType
+	for it.Next() {
+		types = append(types, it.Record().Type())
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+	require.Equal(t, []data.RecordType{data.ArtifactAdd, data.ArtifactAdd, data.RootGroups, data.AllGroups, data.Descriptor}, types)
+	require.Equal(t, uint8(1), it.Version())
+	require.Equal(t, time.UnixMilli(1243533418015).UTC(), it.Timestamp().UTC())
+	require.False(t, it.Next())
+
+	// stop early, and release the resource
+	it = OpenChunk(logger, simpleCfg, target, func(r data.Record) bool {
+		return r.Type() == data.AllGroups
+	})
+	require.True(t, it.Next())
+	require.Equal(t, data.AllGroups, it.Record().Type())
+	require.NoError(t, it.Close())
+	require.False(t, it.Next())
+	require.NoError(t, it.Err())
+
+	// missing chunks surface as errors
+	it = OpenChunk(logger, simpleCfg, simpleCfg.ResolveTarget(".1.gz"), nil)
+	require.False(t, it.Next())
+	require.Error(t, it.Err())
+	require.NoError(t, it.Close())
+}
+
+func TestChunkCancelled(t *testing.T) {
+	logger := log.Default()
+
+	simpleCfg := config.Index{
+		Meta: config.Meta{
+			ID:      "apache-snapshots-local",
+			ChainID: "1243533418968",
+			File:    "nexus-maven-repository-index",
+		},
+		Source: config.Source{
+			Base: "testdata/",
+			Type: config.Local,
+		},
+		Mode: config.Mode{
+			Type: config.All,
+		},
+		Output: config.Output{
+			Format: config.Log,
+		},
+	}
+	require.NoError(t, config.Validate(logger, simpleCfg))
+
+	target := simpleCfg.ResolveTarget(".gz")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := OpenChunkContext(ctx, logger, simpleCfg, target, nil)
+	require.True(t, it.Next())
+	cancel()
+	require.False(t, it.Next())
+	require.Equal(t, context.Canceled, errors.Cause(it.Err()))
+	require.NoError(t, it.Close())
+
+	// an unbuffered, unconsumed queue must not block a cancelled read
+	records := make(chan data.Record)
+	chunk := NewChunk(logger, records, simpleCfg, target, nil)
+	err := chunk.ReadContext(ctx)
+	require.Equal(t, context.Canceled, errors.Cause(err), "(%T) %s", err, err)
+}
+
+func TestChunkVerification(t *testing.T) {
+	logger := log.Default()
+
+	base := t.TempDir() + string(filepath.Separator)
+	content, err := os.ReadFile("testdata/nexus-maven-repository-index.gz")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index.gz", content, 0o644))
+
+	cfg := config.Index{
+		Meta: config.Meta{
+			ID:      "apache-snapshots-local",
+			ChainID: "1243533418968",
+			File:    "nexus-maven-repository-index",
+		},
+		Source: config.Source{
+			Base: base,
+			Type: config.Local,
+		},
+		Verify: config.Verify{
+			Checksums: true,
+		},
+		Mode: config.Mode{
+			Type: config.All,
+		},
+		Output: config.Output{
+			Format: config.Log,
+		},
+	}
+	target := cfg.ResolveTarget(".gz")
+
+	sum := sha1.Sum(content)
+	require.NoError(t, os.WriteFile(target+".sha1", []byte(hex.EncodeToString(sum[:])), 0o644))
+	it := OpenChunk(logger, cfg, target, nil)
+	count := 0
+	for it.Next() {
+		count++
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, 5, count)
+
+	// the chunk's records are read, but the stream fails at its end
+	require.NoError(t, os.WriteFile(target+".sha1", []byte("da39a3ee5e6b4b0d3255bfef95601890afd80709"), 0o644))
+	it = OpenChunk(logger, cfg, target, nil)
+	for it.Next() {
+	}
+	var verr *resources.VerificationError
+	require.True(t, errors.As(it.Err(), &verr), "%s", it.Err())
+	require.Equal(t, resources.CheckSHA1, verr.Check)
+}
+
+func TestChunkTruncated(t *testing.T) {
+	logger := log.Default()
+
+	// version, timestamp, then a single field record:
+	// flags, key "u", and a value of 10 bytes, cut short
+	header := []byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0}
+	record := []byte{0, 0, 0, 1, 0x07, 0, 1, 'u', 0, 0, 0, 10}
+
+	for name, content := range map[string][]byte{
+		"after value length": append(append([]byte{}, header...), record...),
+		"mid value":          append(append(append([]byte{}, header...), record...), "org"...),
+		"mid value length":   append(append([]byte{}, header...), record[:len(record)-2]...),
+		"after field count":  append(append([]byte{}, header...), record[:4]...),
+	} {
+		base := t.TempDir() + string(filepath.Separator)
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		_, err := gzw.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, gzw.Close())
+		require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index.gz", buf.Bytes(), 0o644))
+
+		cfg := config.Index{
+			Meta: config.Meta{
+				ID:      "truncated",
+				ChainID: "1243533418968",
+				File:    "nexus-maven-repository-index",
+			},
+			Source: config.Source{
+				Base: base,
+				Type: config.Local,
+			},
+			Mode: config.Mode{
+				Type: config.All,
+			},
+			Output: config.Output{
+				Format: config.Log,
+			},
+		}
+
+		it := OpenChunk(logger, cfg, cfg.ResolveTarget(".gz"), nil)
+		require.False(t, it.Next(), name)
+		require.ErrorIs(t, it.Err(), io.ErrUnexpectedEOF, name)
+		require.NoError(t, it.Close())
+	}
+}
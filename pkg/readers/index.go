@@ -1,12 +1,17 @@
 package readers
 
 import (
+	"bytes"
 	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
 	"log"
+	"sort"
 	"strconv"
 	"time"
 
-	"github.com/elireisman/maven-index-reader-go/internal/utils"
 	"github.com/elireisman/maven-index-reader-go/pkg/config"
 	"github.com/elireisman/maven-index-reader-go/pkg/data"
 	"github.com/elireisman/maven-index-reader-go/pkg/resources"
@@ -14,6 +19,43 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ErrIncrementalChainBroken - the incremental chunks following the last
+// processed chunk are no longer all published, as when the chain has a gap,
+// or the chunk after the last processed one has aged out of the index's
+// retention window. Records were lost, and only a full index read recovers
+var ErrIncrementalChainBroken = errors.New("incremental chain broken")
+
+// ChainBrokenError - details an ErrIncrementalChainBroken, which it matches
+// in errors.Is. Test for either with IsChainBroken
+type ChainBrokenError struct {
+	// chunk ID of the last processed chunk, from config.Mode.After
+	After int
+
+	// the oldest chunk ID still published, if known
+	Oldest int
+
+	// IDs of chunks expected to follow After that are not published
+	Missing []int
+}
+
+func (cbe *ChainBrokenError) Error() string {
+	msg := fmt.Sprintf("%s: %d chunks missing after chunk %d, from chunk %d",
+		ErrIncrementalChainBroken, len(cbe.Missing), cbe.After, cbe.Missing[0])
+	if cbe.Oldest > 0 {
+		msg += fmt.Sprintf(", oldest available chunk is %d", cbe.Oldest)
+	}
+	return msg
+}
+
+func (cbe *ChainBrokenError) Is(target error) bool {
+	return target == ErrIncrementalChainBroken
+}
+
+// IsChainBroken - whether the error reports an ErrIncrementalChainBroken
+func IsChainBroken(err error) bool {
+	return errors.Is(err, ErrIncrementalChainBroken)
+}
+
 type Index struct {
 	cfg    config.Index
 	logger *log.Logger
@@ -30,59 +72,122 @@ func NewIndex(l *log.Logger, b chan<- string, c config.Index) Index {
 }
 
 func (ir Index) Read() error {
-	// load remote index properties file
-	target := ir.cfg.ResolveTarget(".properties")
-	rsc, err := resources.FromConfig(ir.logger, ir.cfg, target)
-	if err != nil {
+	return ir.ReadContext(context.Background())
+}
+
+// ReadContext - as Read, but stops with the context's error once it is
+// done. The chunk names buffer is closed in either case
+func (ir Index) ReadContext(ctx context.Context) error {
+	defer close(ir.buffer)
+
+	var targetChunks []string
+	props, err := ir.Properties(ctx)
+	switch {
+	case IsChainRotated(err):
+		// chunk IDs restart with the new chain, so only the full index will do
+		if ir.cfg.Mode.Type != config.All && !ir.cfg.Mode.Fallback {
+			return errors.Wrap(err, "from Index#Read")
+		}
+		// the new chain is only adopted, by AdoptChain, once read in full
+		ir.logger.Printf("Index: reading the full index: %s", err)
+		targetChunks = []string{ir.cfg.ResolveTarget(".gz")}
+
+	case err != nil:
 		return errors.Wrap(err, "from Index#Read")
+
+	default:
+		targetChunks, err = ir.planChunks(ctx, props)
+		if err != nil {
+			return errors.Wrap(err, "from Index#Read")
+		}
 	}
 
-	rdr, err := NewProperties(ir.logger, rsc)
+	ir.logger.Printf("Resolved index chunk target list: %v", targetChunks)
+
+	for _, chunkName := range targetChunks {
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "from Index#Read")
+		case ir.buffer <- chunkName:
+		}
+	}
+
+	return nil
+}
+
+// planChunks - the chunks to read from the index described by props
+func (ir Index) planChunks(ctx context.Context, props data.Properties) ([]string, error) {
+	// the full index alone doesn't depend on any incremental
+	if ir.cfg.Mode.Type == config.All {
+		return []string{ir.cfg.ResolveTarget(".gz")}, nil
+	}
+
+	lastIncr, err := props.GetAsInt(data.PropertyLastIncremental)
 	if err != nil {
-		return errors.Wrap(err, "from Index#Read")
+		return nil, err
 	}
+	ir.logger.Printf("Resolved Nexus last incremented chunk index: %d", lastIncr)
 
-	props, err := rdr.Read()
+	listed, err := props.Incrementals()
 	if err != nil {
-		return errors.Wrap(err, "from Index#Read")
+		return nil, err
 	}
 
-	// parse out important properties values
-	tsz, err := props.GetAsTimestamp("nexus.index.timestamp")
+	targetChunks, err := ir.enumerateIndexChunks(ctx, lastIncr, listed)
 	if err != nil {
-		return errors.Wrap(err, "from Index#Read")
+		if !IsChainBroken(err) || !ir.cfg.Mode.Fallback {
+			return nil, err
+		}
+
+		ir.logger.Printf("Index: falling back to reading the full index: %s", err)
+		targetChunks = []string{ir.cfg.ResolveTarget(".gz")}
 	}
-	ir.logger.Printf("Resolved Nexus timestamp: %s", tsz)
 
-	lastIncr, err := props.GetAsInt("nexus.index.last-incremental")
+	return targetChunks, nil
+}
+
+// Properties - load the index properties file, and validate it against the
+// expected config.Index settings. If the index was rebuilt under a new chain
+// ID, the properties are returned along with a *ChainRotatedError
+func (ir Index) Properties(ctx context.Context) (data.Properties, error) {
+	target := ir.cfg.ResolveTarget(".properties")
+	rsc, err := resources.FromConfig(ir.logger, ir.cfg, target)
 	if err != nil {
-		return errors.Wrap(err, "from Index#Read")
+		return data.Properties{}, err
 	}
-	ir.logger.Printf("Resolved Nexus last incremented chunk index: %d", lastIncr)
 
-	// validate fetched properties against expected config.Index settings, or bail
-	if err := ir.validateProperties(props); err != nil {
-		return errors.Wrap(err, "from Index#Read")
+	rdr, err := NewProperties(ir.logger, rsc)
+	if err != nil {
+		return data.Properties{}, err
 	}
 
-	targetChunks, err := ir.enumerateIndexChunks(lastIncr)
+	props, err := rdr.ReadContext(ctx)
 	if err != nil {
-		return errors.Wrap(err, "from Index#Read")
+		return data.Properties{}, err
 	}
 
-	ir.logger.Printf("Resolved index chunk target list: %v", targetChunks)
+	tsz, err := props.GetAsTimestamp(data.PropertyTimestamp)
+	if err != nil {
+		return data.Properties{}, err
+	}
+	ir.logger.Printf("Resolved Nexus timestamp: %s", tsz)
 
-	defer close(ir.buffer)
-	for _, chunkName := range targetChunks {
-		ir.buffer <- chunkName
+	// validate fetched properties against expected config.Index settings, or bail
+	if err := ir.validateProperties(props); err != nil {
+		if IsChainRotated(err) {
+			return props, err
+		}
+		return data.Properties{}, err
 	}
 
-	return nil
+	return props, nil
 }
 
-// resolve the list of URL or file path suffixes to be
-// applied to the base target specified in config.Index
-func (ir Index) enumerateIndexChunks(latestChunkID int) ([]string, error) {
+// resolve the list of URL or file path suffixes to be applied to the base
+// target specified in config.Index. Incremental chunks are planned from the
+// "nexus.index.incremental-N" entries listed in the properties file; only
+// indices that don't list them are probed for each candidate chunk
+func (ir Index) enumerateIndexChunks(ctx context.Context, latestChunkID int, listed []int) ([]string, error) {
 	var out []string
 
 	switch ir.cfg.Mode.Type {
@@ -94,26 +199,38 @@ func (ir Index) enumerateIndexChunks(latestChunkID int) ([]string, error) {
 		if err != nil {
 			return out, errors.Wrapf(err, "Index: failed to parse chunk ID %s with cause", ir.cfg.Mode.After)
 		}
-		candidateChunkID := lastSuccessfulChunk + 1
 
-		// TODO: original (Java) version also checks if successor to latestChunkID is already partially available
+		candidates := ir.candidateChunks(lastSuccessfulChunk+1, latestChunkID, listed)
+		if err := ir.checkChain(lastSuccessfulChunk, latestChunkID, listed, candidates); err != nil {
+			return out, err
+		}
 
-		// incremental chunk suffix is of the form ".<number>.<file_extension>"
-		for candidateChunkID <= latestChunkID {
-			candidate := ir.cfg.ResolveTarget(".%d.gz", candidateChunkID)
-			if err := ir.remoteChunkExists(candidate); err != nil {
-				return out, errors.Wrapf(err, "Index: failed to resolve remote chunk at %s with cause", candidate)
+		for _, chunkID := range candidates {
+			// incremental chunk suffix is of the form ".<number>.<file_extension>"
+			candidate := ir.cfg.ResolveTarget(".%d.gz", chunkID)
+			if len(listed) == 0 {
+				if err := ir.remoteChunkExists(ctx, candidate); err != nil {
+					if resources.IsMissing(err) && ctx.Err() == nil {
+						return out, &ChainBrokenError{After: lastSuccessfulChunk, Missing: []int{chunkID}}
+					}
+					return out, errors.Wrapf(err, "Index: failed to resolve remote chunk at %s with cause", candidate)
+				}
 			}
 
 			if ir.cfg.Verbose {
 				ir.logger.Printf("Index: selected chunk %s", candidate)
 			}
-
 			out = append(out, candidate)
-			candidateChunkID++
-			time.Sleep(500 * time.Millisecond)
 		}
 
+		// as in the Java IndexReader, pick up successors to the latest chunk
+		// that were published before the properties file was updated
+		successors, err := ir.successorChunks(ctx, max(lastSuccessfulChunk, latestChunkID)+1, time.Time{})
+		if err != nil {
+			return out, err
+		}
+		out = append(out, successors...)
+
 	case config.AfterTime:
 		// assumption: cfg.Mode.After is a valid RFC 3339 time string of
 		// the last successfully processed chunk from the previous run
@@ -121,26 +238,20 @@ func (ir Index) enumerateIndexChunks(latestChunkID int) ([]string, error) {
 		if err != nil {
 			return out, errors.Wrapf(err, "Index: failed to parse chunk timestamp %s with cause", ir.cfg.Mode.After)
 		}
-		candidateChunkID := latestChunkID
-
-		for candidateChunkID > 0 {
-			candidate := ir.cfg.ResolveTarget(".%d.gz", candidateChunkID)
-			chunkTime, err := ir.remoteChunkTime(candidate)
-			if err != nil {
-				return out, errors.Wrapf(err, "Index: failed to obtain timestamp of chunk at %s with cause", candidate)
-			}
-			if !chunkTime.After(fromTime) {
-				break
-			}
-
-			if ir.cfg.Verbose {
-				ir.logger.Printf("Index: selected chunk %s with timestamp %s", candidate, chunkTime)
-			}
+		// chunk timestamps are only found in their headers
+		selected, err := ir.chunksAfter(ctx, ir.candidateChunks(1, latestChunkID, listed), fromTime)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, selected...)
 
-			out = append(out, candidate)
-			candidateChunkID--
-			time.Sleep(500 * time.Millisecond)
+		// as in the Java IndexReader, pick up successors to the latest chunk
+		// that were published before the properties file was updated
+		successors, err := ir.successorChunks(ctx, latestChunkID+1, fromTime)
+		if err != nil {
+			return out, err
 		}
+		out = append(out, successors...)
 
 	case config.OnlyChunk:
 		chunkID, err := strconv.Atoi(ir.cfg.Mode.Only)
@@ -149,8 +260,10 @@ func (ir Index) enumerateIndexChunks(latestChunkID int) ([]string, error) {
 		}
 
 		candidate := ir.cfg.ResolveTarget(".%d.gz", chunkID)
-		if err := ir.remoteChunkExists(candidate); err != nil {
-			return out, errors.Wrapf(err, "Index: failed to resolve remote chunk at %s with cause", candidate)
+		if !contains(listed, chunkID) {
+			if err := ir.remoteChunkExists(ctx, candidate); err != nil {
+				return out, errors.Wrapf(err, "Index: failed to resolve remote chunk at %s with cause", candidate)
+			}
 		}
 
 		if ir.cfg.Verbose {
@@ -166,63 +279,262 @@ func (ir Index) enumerateIndexChunks(latestChunkID int) ([]string, error) {
 	return out, nil
 }
 
-func (ir Index) remoteChunkExists(target string) error {
-	resource, err := resources.FromConfig(ir.logger, ir.cfg, target)
-	defer resource.Close()
+// candidateChunks - the IDs of chunks from first to last inclusive, in
+// ascending order. If the properties file lists its incremental chunks,
+// only those listed are candidates, otherwise every ID in range is
+func (ir Index) candidateChunks(first, last int, listed []int) []int {
+	var out []int
+	if len(listed) > 0 {
+		for _, chunkID := range listed {
+			if chunkID >= first && chunkID <= last {
+				out = append(out, chunkID)
+			}
+		}
+		return out
+	}
 
-	_, err = resource.Reader()
-	if err != nil {
-		return errors.Wrapf(err, "Index: failed to verify resource exists at %s with cause", resource)
+	ir.logger.Printf("Index: properties list no incremental chunks, probing chunks %d to %d", first, last)
+	for chunkID := first; chunkID <= last; chunkID++ {
+		out = append(out, chunkID)
+	}
+	return out
+}
+
+// checkChain - confirm the listed chunks continue on from the last processed
+// chunk without gaps. Unlisted chunks are checked as they are probed
+func (ir Index) checkChain(lastSuccessfulChunk, latestChunkID int, listed, candidates []int) error {
+	if len(listed) == 0 || lastSuccessfulChunk >= latestChunkID {
+		return nil
+	}
+
+	var missing []int
+	next := lastSuccessfulChunk + 1
+	for _, chunkID := range candidates {
+		for ; next < chunkID; next++ {
+			missing = append(missing, next)
+		}
+		next = chunkID + 1
+	}
+	for ; next <= latestChunkID; next++ {
+		missing = append(missing, next)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return &ChainBrokenError{After: lastSuccessfulChunk, Oldest: listed[0], Missing: missing}
+}
+
+// successorChunks - probe for chunks beyond those listed in the properties
+// file, from firstChunkID onward, stopping at the first chunk that is missing
+// or malformed. Chunks not strictly newer than after, if set, are skipped
+func (ir Index) successorChunks(ctx context.Context, firstChunkID int, after time.Time) ([]string, error) {
+	var out []string
+
+	for candidateChunkID := firstChunkID; ; candidateChunkID++ {
+		candidate := ir.cfg.ResolveTarget(".%d.gz", candidateChunkID)
+
+		// reading the chunk header verifies it exists and is well formed
+		chunkTime, err := ir.remoteChunkTime(ctx, candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return out, errors.Wrap(ctx.Err(), "Index: chunk enumeration cancelled with cause")
+			}
+			if ir.cfg.Verbose {
+				ir.logger.Printf("Index: no successor chunk available at %s: %s", candidate, err)
+			}
+			return out, nil
+		}
+
+		if !after.IsZero() && !chunkTime.After(after) {
+			continue
+		}
+
+		ir.logger.Printf("Index: selected successor chunk %s with timestamp %s", candidate, chunkTime)
+		out = append(out, candidate)
+	}
+}
+
+// remoteChunkExists - fetch only the first byte, to confirm the chunk exists
+func (ir Index) remoteChunkExists(ctx context.Context, target string) error {
+	if _, err := resources.ReadPrefix(ctx, ir.logger, ir.cfg, target, 1); err != nil {
+		return errors.Wrapf(err, "Index: failed to verify resource exists at %s with cause", target)
 	}
 
 	return nil
 }
 
-func (ir Index) remoteChunkTime(target string) (time.Time, error) {
+// the compressed bytes first requested to decode a chunk header. Usually
+// ample, but doubled on demand up to maxChunkHeaderPrefix
+const (
+	chunkHeaderPrefix    = 512
+	maxChunkHeaderPrefix = 64 * 1024
+)
+
+// remoteChunkTime - decode the version byte and timestamp from the chunk
+// header, fetching only as many leading compressed bytes as needed
+func (ir Index) remoteChunkTime(ctx context.Context, target string) (time.Time, error) {
 	errTime := time.Now().UTC()
 
-	resource, err := resources.FromConfig(ir.logger, ir.cfg, target)
-	defer resource.Close()
+	for n := int64(chunkHeaderPrefix); ; n *= 2 {
+		prefix, err := resources.ReadPrefix(ctx, ir.logger, ir.cfg, target, n)
+		if err != nil {
+			return errTime, errors.Wrapf(err, "Index: failed to verify resource exists at %s with cause", target)
+		}
 
-	rdr, err := resource.Reader()
-	if err != nil {
-		return errTime, errors.Wrapf(err, "Index: failed to verify resource exists at %s with cause", resource)
+		chunkTime, err := decodeChunkTime(prefix)
+		if err == nil {
+			return chunkTime, nil
+		}
+
+		// a prefix shorter than requested is the whole chunk
+		truncated := errors.Cause(err) == io.ErrUnexpectedEOF || errors.Cause(err) == io.EOF
+		if !truncated || int64(len(prefix)) < n || n >= maxChunkHeaderPrefix {
+			return errTime, errors.Wrapf(err, "Index: failed to read chunk %s header with cause", target)
+		}
 	}
+}
 
-	gzRdr, err := gzip.NewReader(rdr)
+func decodeChunkTime(prefix []byte) (time.Time, error) {
+	gzRdr, err := gzip.NewReader(bytes.NewReader(prefix))
 	if err != nil {
-		return errTime, errors.Wrapf(err, "Index: failed to wrap chunk time check for %s in GZIP Reader with cause", resource)
+		return time.Time{}, errors.Wrap(err, "failed to open GZIP stream")
 	}
 	defer gzRdr.Close()
 
-	if _, err := utils.ReadByte(gzRdr); err != nil {
-		return errTime, errors.Wrapf(err, "Index: failed to read chunk %s version with cause", target)
+	// the version byte, then the int64 timestamp
+	var header [9]byte
+	if _, err := io.ReadFull(gzRdr, header[:]); err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to read chunk version and timestamp")
 	}
 
-	unixMillis, err := utils.ReadInt64(gzRdr)
-	if err != nil {
-		return errTime, errors.Wrapf(err, "Index: failed to read chunk %s timestamp with cause", target)
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(header[1:]))).UTC(), nil
+}
+
+// chunksAfter - the candidate chunks with timestamps after fromTime, oldest
+// first. Chunk timestamps increase with chunk IDs, so binary search for the
+// oldest such chunk. Missing chunks have expired, so they must be older
+func (ir Index) chunksAfter(ctx context.Context, candidates []int, fromTime time.Time) ([]string, error) {
+	var searchErr error
+	first := sort.Search(len(candidates), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+
+		candidate := ir.cfg.ResolveTarget(".%d.gz", candidates[i])
+		chunkTime, err := ir.remoteChunkTime(ctx, candidate)
+		if err != nil {
+			if resources.IsMissing(err) && ctx.Err() == nil {
+				ir.logger.Printf("Index: chunk %s is no longer published", candidate)
+				return false
+			}
+			searchErr = errors.Wrapf(err, "Index: failed to obtain timestamp of chunk at %s with cause", candidate)
+			return true
+		}
+
+		if ir.cfg.Verbose {
+			ir.logger.Printf("Index: chunk %s has timestamp %s", candidate, chunkTime)
+		}
+		return chunkTime.After(fromTime)
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	var out []string
+	for i := first; i < len(candidates); i++ {
+		candidate := ir.cfg.ResolveTarget(".%d.gz", candidates[i])
+		if ir.cfg.Verbose {
+			ir.logger.Printf("Index: selected chunk %s", candidate)
+		}
+		out = append(out, candidate)
 	}
 
-	return time.UnixMilli(unixMillis).UTC(), nil
+	return out, nil
 }
 
+// validateProperties - check the published index and chain IDs against those
+// in config.Meta, or if unset, those persisted in config.Meta.Store. On first
+// contact, the published IDs are discovered and persisted there instead
 func (ir Index) validateProperties(props data.Properties) error {
-	indexID, err := props.GetAsString("nexus.index.id")
+	indexID, err := props.GetAsString(data.PropertyID)
+	if err != nil {
+		return err
+	}
+	chainID, err := props.GetAsString(data.PropertyChainID)
 	if err != nil {
 		return err
 	}
-	if ir.cfg.Meta.ID != indexID {
-		return errors.Errorf("failed to validate expected index ID %s, got: %s", ir.cfg.Meta.ID, indexID)
+
+	var stored storedMeta
+	if ir.cfg.Meta.Store != "" {
+		if stored, err = loadMeta(ir.cfg.Meta.Store); err != nil {
+			return err
+		}
 	}
 
-	chainID, err := props.GetAsString("nexus.index.chain-id")
+	expected := storedMeta{ID: ir.cfg.Meta.ID, ChainID: ir.cfg.Meta.ChainID}
+	if expected.ID == "" {
+		expected.ID = stored.ID
+	}
+	if expected.ChainID == "" {
+		expected.ChainID = stored.ChainID
+	}
+
+	if expected.ID != "" && expected.ID != indexID {
+		return errors.Errorf("failed to validate expected index ID %s, got: %s", expected.ID, indexID)
+	}
+	if expected.ChainID != "" && expected.ChainID != chainID {
+		return &ChainRotatedError{ID: indexID, Previous: expected.ChainID, Current: chainID}
+	}
+
+	if ir.cfg.Meta.Store != "" && stored != (storedMeta{ID: indexID, ChainID: chainID}) {
+		ir.logger.Printf("Index: discovered index ID %s and chain ID %s", indexID, chainID)
+		return saveMeta(ir.cfg.Meta.Store, storedMeta{ID: indexID, ChainID: chainID})
+	}
+
+	return nil
+}
+
+// AdoptChain - persist the new chain ID a rotated index was rebuilt under, if
+// the metadata is stored, so that later runs follow on from the new chain.
+// Only call once the full index of the new chain has been read successfully,
+// or a failed read would leave later runs building on a partial one
+func (ir Index) AdoptChain(ctx context.Context) error {
+	if ir.cfg.Meta.Store == "" {
+		return nil
+	}
+
+	props, err := ir.Properties(ctx)
+	if !IsChainRotated(err) {
+		return err
+	}
+
+	indexID, err := props.GetAsString(data.PropertyID)
 	if err != nil {
 		return err
 	}
-	if ir.cfg.Meta.ChainID != chainID {
-		return errors.Errorf("failed to validate expected chain ID %s, got: %s", ir.cfg.Meta.ChainID, chainID)
+	chainID, err := props.GetAsString(data.PropertyChainID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	ir.logger.Printf("Index: adopting chain ID %s for index %s", chainID, indexID)
+	return saveMeta(ir.cfg.Meta.Store, storedMeta{ID: indexID, ChainID: chainID})
+}
+
+func contains(chunkIDs []int, chunkID int) bool {
+	for _, id := range chunkIDs {
+		if id == chunkID {
+			return true
+		}
+	}
+	return false
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
@@ -1,8 +1,20 @@
 package readers
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/elireisman/maven-index-reader-go/pkg/config"
 
@@ -42,3 +54,342 @@ func TestSimpleIndex(t *testing.T) {
 	}
 	require.Equal(t, 1, chunkCount)
 }
+
+func TestIndexSuccessorChunks(t *testing.T) {
+	logger := log.Default()
+
+	// the properties file lists no incremental chunks yet, but chunk 1 has
+	// already been published, followed by a partially uploaded chunk 2
+	base := t.TempDir() + string(filepath.Separator)
+	for src, dst := range map[string]string{
+		".properties": ".properties",
+		".gz":         ".1.gz",
+	} {
+		content, err := os.ReadFile("testdata/nexus-maven-repository-index" + src)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index"+dst, content, 0o644))
+	}
+	require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index.2.gz", []byte{0x1f}, 0o644))
+
+	for mode, after := range map[config.ModeType]string{
+		config.AfterChunk: "0",
+		config.AfterTime:  "2009-05-28T00:00:00Z",
+	} {
+		cfg := config.Index{
+			Meta: config.Meta{
+				ID:      "apache-snapshots-local",
+				ChainID: "1243533418968",
+				File:    "nexus-maven-repository-index",
+			},
+			Source: config.Source{
+				Base: base,
+				Type: config.Local,
+			},
+			Mode: config.Mode{
+				Type:  mode,
+				After: after,
+			},
+			Output: config.Output{
+				Format: config.Log,
+			},
+		}
+		require.NoError(t, config.Validate(logger, cfg))
+
+		chunkNamesQueue := make(chan string, 4)
+		require.NoError(t, NewIndex(logger, chunkNamesQueue, cfg).Read())
+
+		var chunkNames []string
+		for chunkName := range chunkNamesQueue {
+			chunkNames = append(chunkNames, chunkName)
+		}
+		require.Equal(t, []string{base + "nexus-maven-repository-index.1.gz"}, chunkNames)
+	}
+
+	// successors no newer than the last processed chunk are skipped
+	cfg := config.Index{
+		Meta: config.Meta{
+			ID:      "apache-snapshots-local",
+			ChainID: "1243533418968",
+			File:    "nexus-maven-repository-index",
+		},
+		Source: config.Source{
+			Base: base,
+			Type: config.Local,
+		},
+		Mode: config.Mode{
+			Type:  config.AfterTime,
+			After: "2009-05-29T00:00:00Z",
+		},
+		Output: config.Output{
+			Format: config.Log,
+		},
+	}
+	chunkNamesQueue := make(chan string, 4)
+	require.NoError(t, NewIndex(logger, chunkNamesQueue, cfg).Read())
+	_, ok := <-chunkNamesQueue
+	require.False(t, ok)
+}
+
+func TestIndexListedChunks(t *testing.T) {
+	logger := log.Default()
+
+	// chunks 1 and 2 have expired, and are no longer listed or published
+	base := t.TempDir() + string(filepath.Separator)
+	content, err := os.ReadFile("testdata/nexus-maven-repository-index.gz")
+	require.NoError(t, err)
+	for _, suffix := range []string{".3.gz", ".4.gz"} {
+		require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index"+suffix, content, 0o644))
+	}
+	props := strings.Join([]string{
+		"nexus.index.id=apache-snapshots-local",
+		"nexus.index.chain-id=1243533418968",
+		"nexus.index.timestamp=20090528175658.015 +0000",
+		"nexus.index.last-incremental=4",
+		"nexus.index.incremental-0=4",
+		"nexus.index.incremental-1=3",
+	}, "\n")
+	require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index.properties", []byte(props), 0o644))
+
+	for _, tc := range []struct {
+		mode     config.Mode
+		expected []string
+	}{
+		{config.Mode{Type: config.AfterChunk, After: "2"}, []string{".3.gz", ".4.gz"}},
+		{config.Mode{Type: config.AfterChunk, After: "3"}, []string{".4.gz"}},
+		{config.Mode{Type: config.AfterChunk, After: "4"}, nil},
+		{config.Mode{Type: config.AfterTime, After: "2009-05-28T00:00:00Z"}, []string{".3.gz", ".4.gz"}},
+		{config.Mode{Type: config.OnlyChunk, Only: "3"}, []string{".3.gz"}},
+	} {
+		cfg := config.Index{
+			Meta: config.Meta{
+				ID:      "apache-snapshots-local",
+				ChainID: "1243533418968",
+				File:    "nexus-maven-repository-index",
+			},
+			Source: config.Source{
+				Base: base,
+				Type: config.Local,
+			},
+			Mode: tc.mode,
+			Output: config.Output{
+				Format: config.Log,
+			},
+		}
+		require.NoError(t, config.Validate(logger, cfg))
+
+		chunkNamesQueue := make(chan string, 4)
+		require.NoError(t, NewIndex(logger, chunkNamesQueue, cfg).Read(), "%+v", tc.mode)
+
+		var chunkNames []string
+		for chunkName := range chunkNamesQueue {
+			chunkNames = append(chunkNames, strings.TrimPrefix(chunkName, base+"nexus-maven-repository-index"))
+		}
+		require.Equal(t, tc.expected, chunkNames, "%+v", tc.mode)
+	}
+}
+
+func TestIndexChainBroken(t *testing.T) {
+	logger := log.Default()
+
+	content, err := os.ReadFile("testdata/nexus-maven-repository-index.gz")
+	require.NoError(t, err)
+
+	writeIndex := func(props []string, suffixes ...string) string {
+		base := t.TempDir() + string(filepath.Separator)
+		for _, suffix := range suffixes {
+			require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index"+suffix, content, 0o644))
+		}
+		props = append([]string{
+			"nexus.index.id=apache-snapshots-local",
+			"nexus.index.chain-id=1243533418968",
+			"nexus.index.timestamp=20090528175658.015 +0000",
+		}, props...)
+		require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index.properties", []byte(strings.Join(props, "\n")), 0o644))
+		return base
+	}
+
+	for name, tc := range map[string]struct {
+		base     string
+		after    string
+		expected ChainBrokenError
+	}{
+		"expired": {
+			base: writeIndex([]string{
+				"nexus.index.last-incremental=4",
+				"nexus.index.incremental-0=4",
+				"nexus.index.incremental-1=3",
+			}, ".gz", ".3.gz", ".4.gz"),
+			after:    "0",
+			expected: ChainBrokenError{After: 0, Oldest: 3, Missing: []int{1, 2}},
+		},
+		"gap": {
+			base: writeIndex([]string{
+				"nexus.index.last-incremental=4",
+				"nexus.index.incremental-0=4",
+				"nexus.index.incremental-1=2",
+				"nexus.index.incremental-2=1",
+			}, ".gz", ".1.gz", ".2.gz", ".4.gz"),
+			after:    "1",
+			expected: ChainBrokenError{After: 1, Oldest: 1, Missing: []int{3}},
+		},
+		"unlisted": {
+			base:     writeIndex([]string{"nexus.index.last-incremental=2"}, ".gz", ".2.gz"),
+			after:    "0",
+			expected: ChainBrokenError{After: 0, Missing: []int{1}},
+		},
+	} {
+		cfg := config.Index{
+			Meta: config.Meta{
+				ID:      "apache-snapshots-local",
+				ChainID: "1243533418968",
+				File:    "nexus-maven-repository-index",
+			},
+			Source: config.Source{
+				Base: tc.base,
+				Type: config.Local,
+			},
+			Mode: config.Mode{
+				Type:  config.AfterChunk,
+				After: tc.after,
+			},
+			Output: config.Output{
+				Format: config.Log,
+			},
+		}
+		require.NoError(t, config.Validate(logger, cfg))
+
+		err := NewIndex(logger, make(chan string, 4), cfg).Read()
+		require.Error(t, err, name)
+		require.True(t, IsChainBroken(err), name)
+		var broken *ChainBrokenError
+		require.ErrorAs(t, err, &broken, name)
+		require.Equal(t, tc.expected, *broken, name)
+
+		// with fallback enabled, the full index is read instead
+		cfg.Mode.Fallback = true
+		chunkNamesQueue := make(chan string, 4)
+		require.NoError(t, NewIndex(logger, chunkNamesQueue, cfg).Read(), name)
+
+		var chunkNames []string
+		for chunkName := range chunkNamesQueue {
+			chunkNames = append(chunkNames, strings.TrimPrefix(chunkName, tc.base))
+		}
+		require.Equal(t, []string{"nexus-maven-repository-index.gz"}, chunkNames, name)
+	}
+}
+
+func writeEmptyChunk(t *testing.T, path string, ts time.Time) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	require.NoError(t, binary.Write(gz, binary.BigEndian, uint8(1)))
+	require.NoError(t, binary.Write(gz, binary.BigEndian, ts.UnixMilli()))
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
+func TestIndexAfterTimeSearch(t *testing.T) {
+	logger := log.Default()
+
+	// 64 hourly chunks, of which the first 8 have expired, followed by
+	// 2 successors published ahead of the properties file
+	dir := t.TempDir()
+	start := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	props := []string{
+		"nexus.index.id=apache-snapshots-local",
+		"nexus.index.chain-id=1243533418968",
+		"nexus.index.timestamp=20220903160000.000 +0000",
+		"nexus.index.last-incremental=64",
+	}
+	for chunkID := 1; chunkID <= 66; chunkID++ {
+		if chunkID > 8 {
+			writeEmptyChunk(t, filepath.Join(dir, fmt.Sprintf("nexus-maven-repository-index.%d.gz", chunkID)), start.Add(time.Duration(chunkID)*time.Hour))
+		}
+	}
+	content := []byte(strings.Join(props, "\n"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nexus-maven-repository-index.properties"), content, 0o644))
+
+	var requests []string
+	var mu sync.Mutex
+	files := http.FileServer(http.Dir(dir))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".gz") {
+			require.Equal(t, "bytes=0-511", r.Header.Get("Range"))
+			mu.Lock()
+			requests = append(requests, r.URL.Path)
+			mu.Unlock()
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	cfg := config.Index{
+		Meta: config.Meta{
+			ID:      "apache-snapshots-local",
+			ChainID: "1243533418968",
+			File:    "nexus-maven-repository-index",
+		},
+		Source: config.Source{
+			Base: srv.URL + "/",
+			Type: config.HTTP,
+		},
+		Mode: config.Mode{
+			Type:  config.AfterTime,
+			After: start.Add(40*time.Hour + time.Minute).Format(time.RFC3339),
+		},
+		Output: config.Output{
+			Format: config.Log,
+		},
+	}
+	require.NoError(t, config.Validate(logger, cfg))
+
+	chunkNamesQueue := make(chan string, 64)
+	require.NoError(t, NewIndex(logger, chunkNamesQueue, cfg).Read())
+
+	var chunkNames []string
+	for chunkName := range chunkNamesQueue {
+		chunkNames = append(chunkNames, chunkName)
+	}
+	// chunks are emitted oldest first, as they must be applied
+	var expected []string
+	for chunkID := 41; chunkID <= 66; chunkID++ {
+		expected = append(expected, cfg.ResolveTarget(".%d.gz", chunkID))
+	}
+	require.Equal(t, expected, chunkNames)
+
+	// a binary search over the 64 chunks, plus probes for chunks 65 to 67
+	require.LessOrEqual(t, len(requests), 10, "%v", requests)
+}
+
+func TestRemoteChunkTime(t *testing.T) {
+	logger := log.Default()
+	base := t.TempDir() + string(filepath.Separator)
+	cfg := config.Index{
+		Meta:   config.Meta{File: "nexus-maven-repository-index"},
+		Source: config.Source{Base: base, Type: config.Local},
+	}
+	ir := NewIndex(logger, nil, cfg)
+	ts := time.UnixMilli(1243533418015).UTC()
+
+	// a GZIP header too large for the initial prefix
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Header.Extra = bytes.Repeat([]byte{0x2a}, 3000)
+	require.NoError(t, binary.Write(gz, binary.BigEndian, uint8(1)))
+	require.NoError(t, binary.Write(gz, binary.BigEndian, ts.UnixMilli()))
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(cfg.ResolveTarget(".1.gz"), buf.Bytes(), 0o644))
+
+	chunkTime, err := ir.remoteChunkTime(context.Background(), cfg.ResolveTarget(".1.gz"))
+	require.NoError(t, err)
+	require.Equal(t, ts, chunkTime)
+
+	// truncated chunks are malformed
+	require.NoError(t, os.WriteFile(cfg.ResolveTarget(".2.gz"), buf.Bytes()[:3010], 0o644))
+	_, err = ir.remoteChunkTime(context.Background(), cfg.ResolveTarget(".2.gz"))
+	require.Error(t, err)
+
+	writeEmptyChunk(t, cfg.ResolveTarget(".3.gz"), ts)
+	chunkTime, err = ir.remoteChunkTime(context.Background(), cfg.ResolveTarget(".3.gz"))
+	require.NoError(t, err)
+	require.Equal(t, ts, chunkTime)
+}
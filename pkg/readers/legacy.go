@@ -0,0 +1,322 @@
+package readers
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/elireisman/maven-index-reader-go/internal/lucene"
+	"github.com/elireisman/maven-index-reader-go/internal/utils"
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/resources"
+
+	"github.com/pkg/errors"
+)
+
+// LegacyIndex - reads the legacy "nexus-maven-repository-index.zip"
+// format, a packed Lucene index, published by older Nexus instances
+// and mirrors in place of (or alongside) the ".gz" transport format
+type LegacyIndex struct {
+	target   string
+	cfg      config.Index
+	logger   *log.Logger
+	buffer   chan<- data.Record
+	filterFn FilterFunc
+}
+
+// NewLegacyIndex - caller supplies the input resource as well as the
+// output channel for captured records that the caller plans to consume
+func NewLegacyIndex(l *log.Logger, b chan<- data.Record, c config.Index, t string, ff FilterFunc) LegacyIndex {
+	return LegacyIndex{
+		target:   t,
+		cfg:      c,
+		logger:   l,
+		buffer:   b,
+		filterFn: ff,
+	}
+}
+
+// Read - consume the Resource and populate the data.Record buffer. As with
+// Chunk, the error returned on success wraps io.EOF. Callers who prefer
+// to pull records synchronously should use OpenLegacyIndex
+func (li LegacyIndex) Read() error {
+	return li.ReadContext(context.Background())
+}
+
+// ReadContext - as Read, but stops with the context's error once it is done
+func (li LegacyIndex) ReadContext(ctx context.Context) error {
+	it := OpenLegacyIndexContext(ctx, li.logger, li.cfg, li.target, li.filterFn)
+	defer it.Close()
+
+	for it.Next() {
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "LegacyIndex(%s): cancelled after %d records with cause", li.target, it.count-1)
+		case li.buffer <- it.Record():
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	li.logger.Printf("LegacyIndex: successfully published %d records from %s", it.count, li.target)
+	return errors.Wrapf(io.EOF, "LegacyIndex(%s): read to completion", li.target)
+}
+
+// LegacyIndexIterator - pulls data.Records from a legacy index one at a
+// time, with the same contract as ChunkIterator. The zip is downloaded
+// and unpacked into a temporary directory, removed again on Close
+type LegacyIndexIterator struct {
+	ctx      context.Context
+	target   string
+	cfg      config.Index
+	logger   *log.Logger
+	filterFn FilterFunc
+
+	tmpDir string
+	index  *lucene.Reader
+
+	record data.Record
+	count  int
+	done   bool
+	err    error
+}
+
+// OpenLegacyIndex - resolve an iterator over the data.Records in the target
+// legacy index that pass the optional filter. The index is fetched on the
+// first call to Next, and released at end of stream, on error, or on Close
+func OpenLegacyIndex(l *log.Logger, c config.Index, t string, ff FilterFunc) *LegacyIndexIterator {
+	return OpenLegacyIndexContext(context.Background(), l, c, t, ff)
+}
+
+// OpenLegacyIndexContext - as OpenLegacyIndex, but the iterator stops with
+// the context's error, and releases the index, once the context is done
+func OpenLegacyIndexContext(ctx context.Context, l *log.Logger, c config.Index, t string, ff FilterFunc) *LegacyIndexIterator {
+	return &LegacyIndexIterator{
+		ctx:      ctx,
+		target:   t,
+		cfg:      c,
+		logger:   l,
+		filterFn: ff,
+	}
+}
+
+// Next - advance to the next data.Record, returning false
+// at the end of the index or if an error was encountered
+func (it *LegacyIndexIterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+
+	if it.index == nil {
+		if err := it.open(); err != nil {
+			it.fail(err)
+			return false
+		}
+	}
+
+	for {
+		if err := it.ctx.Err(); err != nil {
+			it.fail(errors.Wrapf(err, "LegacyIndex(%s): cancelled after %d records with cause", it.target, it.count))
+			return false
+		}
+
+		doc, err := it.index.Next()
+		if err == io.EOF {
+			it.done = true
+			it.release()
+			return false
+		}
+		if err != nil {
+			it.fail(errors.Wrapf(err, "LegacyIndex(%s): failed to read document for record %d with cause", it.target, it.count+1))
+			return false
+		}
+
+		rawRecord := make([]data.RawField, 0, len(doc))
+		for _, field := range doc {
+			rawRecord = append(rawRecord, data.RawField{Key: field.Name, Value: field.Value, Flags: fieldFlags(field)})
+		}
+
+		// parse raw captured KVs into a Record
+		record, rErr := data.NewRecordFromFields(it.logger, rawRecord)
+
+		// as with Chunk, filter before caring about parsing errors
+		if it.filterFn != nil && !it.filterFn(record) {
+			if it.cfg.Verbose {
+				it.logger.Printf("LegacyIndex(%s): skipping filtered record: %+v", it.target, record)
+			}
+			continue
+		}
+
+		if rErr != nil {
+			it.fail(errors.Wrapf(rErr,
+				"LegacyIndex(%s): failed to compose well-formed record %d from %+v with cause",
+				it.target, it.count+1, rawRecord))
+			return false
+		}
+
+		it.record = record
+		it.count++
+		return true
+	}
+}
+
+// Record - the data.Record resolved by the latest call to Next
+func (it *LegacyIndexIterator) Record() data.Record {
+	return it.record
+}
+
+// Err - the first error encountered by the iterator, if any.
+// Reaching the end of the index is not an error
+func (it *LegacyIndexIterator) Err() error {
+	return it.err
+}
+
+// Close - release the index and remove its temporary files. Safe
+// to call repeatedly, and after the iterator is exhausted
+func (it *LegacyIndexIterator) Close() error {
+	it.done = true
+	return it.release()
+}
+
+func (it *LegacyIndexIterator) open() error {
+	tmpDir, err := os.MkdirTemp("", "maven-index-legacy-")
+	if err != nil {
+		return errors.Wrapf(err, "LegacyIndex(%s): failed to create temporary directory with cause", it.target)
+	}
+	it.tmpDir = tmpDir
+
+	// zip archives are read from the end, so spool the resource to disk first
+	archive := filepath.Join(tmpDir, "index.zip")
+	if err := it.download(archive); err != nil {
+		return err
+	}
+
+	indexDir := filepath.Join(tmpDir, "index")
+	if err := unpack(it.ctx, archive, indexDir); err != nil {
+		return errors.Wrapf(err, "LegacyIndex(%s): failed to unpack archive with cause", it.target)
+	}
+
+	index, err := lucene.Open(indexDir)
+	if err != nil {
+		return errors.Wrapf(err, "LegacyIndex(%s): failed to open Lucene index with cause", it.target)
+	}
+	it.index = index
+	it.logger.Printf("LegacyIndex(%s): opened index with %d segments", it.target, len(index.Segments()))
+
+	return nil
+}
+
+func (it *LegacyIndexIterator) download(path string) error {
+	resource, err := resources.FromConfig(it.logger, it.cfg, it.target)
+	if err != nil {
+		return errors.Wrapf(err, "LegacyIndex: failed to resolve resource %s with cause", it.target)
+	}
+	defer resource.Close()
+
+	rdr, err := resource.ReaderContext(it.ctx)
+	if err != nil {
+		return errors.Wrapf(err, "LegacyIndex: failed to obtain data stream from %s with cause", resource)
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "LegacyIndex(%s): failed to create %s with cause", it.target, path)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, utils.NewContextReader(it.ctx, rdr)); err != nil {
+		return errors.Wrapf(err, "LegacyIndex: failed to download %s with cause", resource)
+	}
+
+	return out.Close()
+}
+
+func (it *LegacyIndexIterator) fail(err error) {
+	it.err = err
+	it.release()
+}
+
+func (it *LegacyIndexIterator) release() error {
+	var err error
+	if it.index != nil {
+		err = it.index.Close()
+		it.index = nil
+	}
+	if it.tmpDir != "" {
+		if rErr := os.RemoveAll(it.tmpDir); rErr != nil && err == nil {
+			err = rErr
+		}
+		it.tmpDir = ""
+	}
+
+	return err
+}
+
+// unpack - extract the flat list of Lucene index files in the archive into dir
+func unpack(ctx context.Context, archive, dir string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		// index files are never nested, and entry names must not escape dir
+		name := filepath.Base(filepath.FromSlash(f.Name))
+		if err := unpackFile(ctx, f, filepath.Join(dir, name)); err != nil {
+			return errors.Wrapf(err, "failed to extract %s", f.Name)
+		}
+	}
+
+	return nil
+}
+
+func unpackFile(ctx context.Context, f *zip.File, path string) error {
+	in, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, utils.NewContextReader(ctx, in)); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+// fieldFlags - Lucene only yields stored fields, which are flagged as
+// such alongside the indexing options recorded in the segment
+func fieldFlags(field lucene.Field) data.FieldFlags {
+	flags := data.FlagStored
+	if field.Indexed {
+		flags |= data.FlagIndexed
+	}
+	if field.Tokenized {
+		flags |= data.FlagTokenized
+	}
+	if field.Compressed {
+		flags |= data.FlagCompressed
+	}
+
+	return flags
+}
@@ -0,0 +1,116 @@
+package readers
+
+import (
+	"io"
+	"log"
+	"testing"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func legacyTestConfig() config.Index {
+	return config.Index{
+		Meta: config.Meta{
+			ID:      "legacy-test",
+			ChainID: "1243533418968",
+			File:    "nexus-maven-repository-index",
+		},
+		Source: config.Source{
+			Base: "testdata/",
+			Type: config.Local,
+		},
+		Mode: config.Mode{
+			Type: config.All,
+		},
+		Output: config.Output{
+			Format: config.Log,
+		},
+	}
+}
+
+func TestLegacyIndexIterator(t *testing.T) {
+	logger := log.Default()
+	cfg := legacyTestConfig()
+	require.NoError(t, config.Validate(logger, cfg))
+
+	it := OpenLegacyIndex(logger, cfg, cfg.ResolveTarget(".zip"), nil)
+	var records []data.Record
+	for it.Next() {
+		records = append(records, it.Record())
+	}
+	require.NoError(t, it.Err())
+	require.NoError(t, it.Close())
+	require.False(t, it.Next())
+
+	var types []data.RecordType
+	for _, r := range records {
+		types = append(types, r.Type())
+	}
+	require.Equal(t, []data.RecordType{
+		data.Descriptor, data.ArtifactAdd, data.ArtifactAdd,
+		data.ArtifactRemove, data.AllGroups, data.RootGroups,
+	}, types)
+
+	widget, err := records[1].AsArtifactAdd()
+	require.NoError(t, err)
+	require.Equal(t, "org.example", widget.GroupID)
+	require.Equal(t, "widget", widget.ArtifactID)
+	require.Equal(t, "1.0", widget.Version)
+	require.Equal(t, int64(1024), widget.FileSize)
+	require.Equal(t, "38bb5a445e9aa5a38581743ede58f46c0f1ce321", widget.SHA1)
+
+	gadget, err := records[2].AsArtifactAdd()
+	require.NoError(t, err)
+	require.Equal(t, "Gadget €", gadget.Name)
+
+	// flags reflect the field options stored in the Lucene segment
+	uFlags, ok := records[1].Flags(data.UInfoKey)
+	require.True(t, ok)
+	require.True(t, uFlags.Indexed())
+	require.True(t, uFlags.Stored())
+	iFlags, ok := records[1].Flags(data.InfoKey)
+	require.True(t, ok)
+	require.False(t, iFlags.Indexed())
+	nFlags, ok := records[1].Flags("n")
+	require.True(t, ok)
+	require.True(t, nFlags.Tokenized())
+
+	removed, err := records[3].AsArtifactRemove()
+	require.NoError(t, err)
+	require.Equal(t, "gizmo", removed.ArtifactID)
+
+	groups, err := records[4].AsGroups()
+	require.NoError(t, err)
+	require.Equal(t, []string{"org.example"}, groups.Groups)
+}
+
+func TestLegacyIndexRead(t *testing.T) {
+	logger := log.Default()
+	cfg := legacyTestConfig()
+
+	buffer := make(chan data.Record, 8)
+	legacy := NewLegacyIndex(logger, buffer, cfg, cfg.ResolveTarget(".zip"), func(r data.Record) bool {
+		return r.Type() == data.ArtifactAdd
+	})
+	err := legacy.Read()
+	require.Error(t, err)
+	require.Equal(t, io.EOF, errors.Cause(err))
+	close(buffer)
+
+	count := 0
+	for r := range buffer {
+		require.Equal(t, data.ArtifactAdd, r.Type())
+		count++
+	}
+	require.Equal(t, 2, count)
+
+	// missing archives surface as errors
+	it := OpenLegacyIndex(logger, cfg, cfg.ResolveTarget(".1.zip"), nil)
+	require.False(t, it.Next())
+	require.Error(t, it.Err())
+	require.NoError(t, it.Close())
+}
@@ -0,0 +1,89 @@
+package readers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ErrChainRotated - the index was rebuilt under a new chain ID since it
+// was last read, restarting its chunk IDs. Incremental reads can't follow
+// on from the previous chain, so only a full index read recovers
+var ErrChainRotated = errors.New("index chain rotated")
+
+// ChainRotatedError - details an ErrChainRotated, which it matches
+// in errors.Is. Test for either with IsChainRotated
+type ChainRotatedError struct {
+	// the index ID, as in "nexus.index.id"
+	ID string
+
+	// the expected chain ID, and the one now published
+	Previous string
+	Current  string
+}
+
+func (cre *ChainRotatedError) Error() string {
+	return fmt.Sprintf("%s: index %s chain ID changed from %s to %s, a full re-ingest is required",
+		ErrChainRotated, cre.ID, cre.Previous, cre.Current)
+}
+
+func (cre *ChainRotatedError) Is(target error) bool {
+	return target == ErrChainRotated
+}
+
+// IsChainRotated - whether the error reports an ErrChainRotated
+func IsChainRotated(err error) bool {
+	return errors.Is(err, ErrChainRotated)
+}
+
+// storedMeta - the index metadata persisted in config.Meta.Store
+type storedMeta struct {
+	ID      string `json:"id"`
+	ChainID string `json:"chainId"`
+}
+
+// loadMeta - the metadata persisted at path, if any
+func loadMeta(path string) (storedMeta, error) {
+	var out storedMeta
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return out, errors.Wrapf(err, "Index: failed to read stored metadata %s with cause", path)
+	}
+
+	if err := json.Unmarshal(content, &out); err != nil {
+		return out, errors.Wrapf(err, "Index: failed to parse stored metadata %s with cause", path)
+	}
+
+	return out, nil
+}
+
+// saveMeta - persist the metadata at path, moving it into place once complete
+func saveMeta(path string, meta storedMeta) error {
+	content, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Index: failed to encode metadata with cause")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrapf(err, "Index: failed to create directory for %s with cause", path)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(content, '\n'), 0o644); err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "Index: failed to write metadata %s with cause", tmp)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "Index: failed to move %s into place with cause", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package readers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexMetaDiscovery(t *testing.T) {
+	logger := log.Default()
+
+	base := t.TempDir() + string(filepath.Separator)
+	for _, suffix := range []string{".gz", ".properties"} {
+		content, err := os.ReadFile("testdata/nexus-maven-repository-index" + suffix)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index"+suffix, content, 0o644))
+	}
+	store := filepath.Join(t.TempDir(), "meta", "index.json")
+
+	cfg := config.Index{
+		Meta: config.Meta{
+			File:  "nexus-maven-repository-index",
+			Store: store,
+		},
+		Source: config.Source{
+			Base: base,
+			Type: config.Local,
+		},
+		Mode: config.Mode{
+			Type: config.All,
+		},
+		Output: config.Output{
+			Format: config.Log,
+		},
+	}
+	require.NoError(t, config.Validate(logger, cfg))
+
+	readIndex := func(cfg config.Index) ([]string, error) {
+		chunkNamesQueue := make(chan string, 4)
+		err := NewIndex(logger, chunkNamesQueue, cfg).Read()
+
+		var chunkNames []string
+		for chunkName := range chunkNamesQueue {
+			chunkNames = append(chunkNames, strings.TrimPrefix(chunkName, base))
+		}
+		return chunkNames, err
+	}
+	requireStored := func(expected storedMeta) {
+		content, err := os.ReadFile(store)
+		require.NoError(t, err)
+		var stored storedMeta
+		require.NoError(t, json.Unmarshal(content, &stored))
+		require.Equal(t, expected, stored)
+	}
+
+	// discovered on first contact, and expected thereafter
+	chunkNames, err := readIndex(cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{"nexus-maven-repository-index.gz"}, chunkNames)
+	requireStored(storedMeta{ID: "apache-snapshots-local", ChainID: "1243533418968"})
+
+	_, err = readIndex(cfg)
+	require.NoError(t, err)
+
+	// the index is rebuilt under a new chain ID
+	content, err := os.ReadFile(base + "nexus-maven-repository-index.properties")
+	require.NoError(t, err)
+	rotated := strings.Replace(string(content), "chain-id=1243533418968", "chain-id=1243533419999", 1)
+	require.NotEqual(t, string(content), rotated)
+	require.NoError(t, os.WriteFile(base+"nexus-maven-repository-index.properties", []byte(rotated), 0o644))
+
+	// incremental reads can't follow on from the previous chain
+	incremental := cfg
+	incremental.Mode = config.Mode{Type: config.AfterChunk, After: "0"}
+	chunkNames, err = readIndex(incremental)
+	require.True(t, IsChainRotated(err), "%v", err)
+	var cre *ChainRotatedError
+	require.ErrorAs(t, err, &cre)
+	require.Equal(t, ChainRotatedError{ID: "apache-snapshots-local", Previous: "1243533418968", Current: "1243533419999"}, *cre)
+	require.Empty(t, chunkNames)
+	requireStored(storedMeta{ID: "apache-snapshots-local", ChainID: "1243533418968"})
+
+	// an explicit chain ID is expected over the stored one
+	explicit := cfg
+	explicit.Meta.ChainID = "1243533418968"
+	explicit.Mode = incremental.Mode
+	_, err = readIndex(explicit)
+	require.True(t, IsChainRotated(err), "%v", err)
+
+	// unless falling back to a full read, and once read, adopting the new chain
+	incremental.Mode.Fallback = true
+	chunkNames, err = readIndex(incremental)
+	require.NoError(t, err)
+	require.Equal(t, []string{"nexus-maven-repository-index.gz"}, chunkNames)
+	requireStored(storedMeta{ID: "apache-snapshots-local", ChainID: "1243533418968"})
+	require.NoError(t, NewIndex(logger, nil, incremental).AdoptChain(context.Background()))
+	requireStored(storedMeta{ID: "apache-snapshots-local", ChainID: "1243533419999"})
+	require.NoError(t, NewIndex(logger, nil, incremental).AdoptChain(context.Background()))
+
+	// a different index entirely is still rejected
+	other := cfg
+	other.Meta.ID = "central"
+	_, err = readIndex(other)
+	require.Error(t, err)
+	require.False(t, IsChainRotated(err))
+}
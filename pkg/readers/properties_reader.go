@@ -1,10 +1,13 @@
 package readers
 
 import (
+	"context"
 	"io/ioutil"
 	"log"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf16"
 
 	"github.com/elireisman/maven-index-reader-go/internal/utils"
 	"github.com/elireisman/maven-index-reader-go/pkg/data"
@@ -13,7 +16,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-var linesPattern = regexp.MustCompile(`[\r\n]+`)
+var linesPattern = regexp.MustCompile(`\r\n|\r|\n`)
 
 type PropertiesReader struct {
 	logger   *log.Logger
@@ -33,8 +36,13 @@ func NewProperties(l *log.Logger, r resources.Resource) (PropertiesReader, error
 // files of interest when performing a full backfill or incremental
 // update.
 func (pr PropertiesReader) Read() (data.Properties, error) {
+	return pr.ReadContext(context.Background())
+}
+
+// ReadContext - as Read, but fails with the context's error once it is done
+func (pr PropertiesReader) ReadContext(ctx context.Context) (data.Properties, error) {
 	pr.logger.Printf("PropertiesReader: consuming %+v", pr.resource)
-	rdr, err := pr.resource.Reader()
+	rdr, err := pr.resource.ReaderContext(ctx)
 	if err != nil {
 		return data.Properties{}, errors.Wrap(err, "PropertiesReader: failed to read data from Resource with cause")
 	}
@@ -47,26 +55,157 @@ func (pr PropertiesReader) Read() (data.Properties, error) {
 
 	goStr, err := utils.GetString(raw)
 	if err != nil {
-		return data.Properties{}, errors.Wrap(err, "PropertiesReader: failed to convert data bytes from Java 'modified' UTF-8 with cause")
+		// java.util.Properties#store writes ISO 8859-1, escaping
+		// anything else. tolerate files with raw Latin-1 chars
+		goStr = decodeLatin1(raw)
+	}
+
+	out, err := ParseProperties(goStr)
+	if err != nil {
+		return data.Properties{}, errors.Wrap(err, "PropertiesReader: failed to parse properties with cause")
 	}
 
+	return data.NewProperties(out), nil
+}
+
+// ParseProperties - parse the content of a Java properties file, following
+// the rules of java.util.Properties#load: "#" and "!" comments, "=", ":" or
+// whitespace key separators, backslash line continuations and escapes.
+// See: https://docs.oracle.com/javase/8/docs/api/java/util/Properties.html#load-java.io.Reader-
+func ParseProperties(content string) (map[string]string, error) {
 	out := map[string]string{}
-	for ndx, line := range linesPattern.Split(goStr, -1) {
-		// skip commented out lines
-		if strings.HasPrefix(line, "#") || line == "" {
-			continue
+
+	var logical strings.Builder
+	continuation := false
+	lines := linesPattern.Split(content, -1)
+	for ndx, line := range lines {
+		line = strings.TrimLeft(line, " \t\f")
+
+		if !continuation {
+			// skip blank and commented out lines
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+				continue
+			}
+			logical.Reset()
+		}
+
+		// an odd number of trailing backslashes escapes the line terminator
+		trailing := len(line) - len(strings.TrimRight(line, `\`))
+		continuation = trailing%2 == 1
+		if continuation {
+			line = line[:len(line)-1]
 		}
+		logical.WriteString(line)
 
-		key, value, found := strings.Cut(line, "=")
-		if !found {
-			return data.Properties{}, errors.Errorf("PropertiesReader: line %d failed to parse into key and value: %s", ndx, line)
+		if continuation && ndx < len(lines)-1 {
+			continue
 		}
 
-		key = strings.TrimSpace(key)
-		value = strings.TrimSpace(value)
-		//pr.logger.Printf("%16s => %24s", key, value)
+		key, value, err := splitProperty(logical.String())
+		if err != nil {
+			return nil, errors.Wrapf(err, "ParseProperties: line %d failed to parse into key and value", ndx+1)
+		}
 		out[key] = value
 	}
 
-	return data.NewProperties(out), nil
+	return out, nil
+}
+
+// split a logical line on the first unescaped "=", ":" or whitespace, then unescape
+func splitProperty(line string) (string, string, error) {
+	keyEnd := len(line)
+	valueStart := len(line)
+
+	escaped := false
+	for ndx := 0; ndx < len(line); ndx++ {
+		ch := line[ndx]
+		if escaped {
+			escaped = false
+			continue
+		}
+		if ch == '\\' {
+			escaped = true
+			continue
+		}
+		if ch == '=' || ch == ':' || ch == ' ' || ch == '\t' || ch == '\f' {
+			keyEnd = ndx
+
+			// whitespace may surround a single "=" or ":" separator
+			rest := strings.TrimLeft(line[ndx:], " \t\f")
+			if ch == '=' || ch == ':' {
+				rest = strings.TrimLeft(line[ndx+1:], " \t\f")
+			} else if strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, ":") {
+				rest = strings.TrimLeft(rest[1:], " \t\f")
+			}
+			valueStart = len(line) - len(rest)
+			break
+		}
+	}
+
+	key, err := unescapeProperty(line[:keyEnd])
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to unescape key %q with cause", line[:keyEnd])
+	}
+	value, err := unescapeProperty(line[valueStart:])
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to unescape value for key %q with cause", key)
+	}
+
+	return key, value, nil
+}
+
+func unescapeProperty(escaped string) (string, error) {
+	if !strings.Contains(escaped, `\`) {
+		return escaped, nil
+	}
+
+	// collect UTF-16 code units, so escaped surrogate pairs recombine
+	var out []uint16
+	runes := []rune(escaped)
+	for ndx := 0; ndx < len(runes); ndx++ {
+		ch := runes[ndx]
+		if ch != '\\' {
+			out = append(out, utf16.Encode([]rune{ch})...)
+			continue
+		}
+		// a lone backslash at the end of input escapes nothing, and is dropped
+		if ndx == len(runes)-1 {
+			break
+		}
+
+		ndx++
+		switch runes[ndx] {
+		case 't':
+			out = append(out, '\t')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 'f':
+			out = append(out, '\f')
+		case 'u':
+			if ndx+4 >= len(runes) {
+				return "", errors.Errorf("malformed \\uxxxx encoding at offset %d", ndx-1)
+			}
+			code, err := strconv.ParseUint(string(runes[ndx+1:ndx+5]), 16, 16)
+			if err != nil {
+				return "", errors.Errorf("malformed \\uxxxx encoding at offset %d", ndx-1)
+			}
+			out = append(out, uint16(code))
+			ndx += 4
+		default:
+			out = append(out, utf16.Encode([]rune{runes[ndx]})...)
+		}
+	}
+
+	return string(utf16.Decode(out)), nil
+}
+
+func decodeLatin1(raw []byte) string {
+	out := make([]rune, len(raw))
+	for ndx, b := range raw {
+		out[ndx] = rune(b)
+	}
+
+	return string(out)
 }
@@ -0,0 +1,120 @@
+package readers
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/resources"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimpleProperties(t *testing.T) {
+	logger := log.Default()
+
+	rsc, err := resources.NewLocalResource(logger, "testdata/nexus-maven-repository-index.properties")
+	require.NoError(t, err)
+
+	rdr, err := NewProperties(logger, rsc)
+	require.NoError(t, err)
+
+	props, err := rdr.Read()
+	require.NoError(t, err)
+
+	id, err := props.GetAsString(data.PropertyID)
+	require.NoError(t, err)
+	require.Equal(t, "apache-snapshots-local", id)
+
+	chainID, err := props.GetAsString(data.PropertyChainID)
+	require.NoError(t, err)
+	require.Equal(t, "1243533418968", chainID)
+
+	lastIncr, err := props.GetAsInt(data.PropertyLastIncremental)
+	require.NoError(t, err)
+	require.Equal(t, 0, lastIncr)
+
+	ts, err := props.GetAsTimestamp(data.PropertyTimestamp)
+	require.NoError(t, err)
+	require.Equal(t, time.UnixMilli(1243533418015).UTC(), ts.UTC())
+}
+
+func TestLatin1Properties(t *testing.T) {
+	logger := log.Default()
+
+	// raw Latin-1 high bytes, including one ending the file, which isn't
+	// the lead byte of a complete modified UTF-8 sequence
+	for content, expected := range map[string]string{
+		"a=caf\xe9\n":       "café",
+		"a=caf\xe9":         "café",
+		"a=\xe9t\xe9\n":     "été",
+		"a=caf\xc3":         "caf\u00c3",
+		"a=na\xefve\nb=2\n": "naïve",
+	} {
+		path := filepath.Join(t.TempDir(), "latin1.properties")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+		rsc, err := resources.NewLocalResource(logger, path)
+		require.NoError(t, err)
+		rdr, err := NewProperties(logger, rsc)
+		require.NoError(t, err)
+
+		props, err := rdr.Read()
+		require.NoError(t, err, "%q", content)
+		value, err := props.GetAsString("a")
+		require.NoError(t, err)
+		require.Equal(t, expected, value, "%q", content)
+	}
+}
+
+func TestParseProperties(t *testing.T) {
+	content := "# comment\r\n" +
+		"! also a comment\n" +
+		"   \n" +
+		"colon:value\n" +
+		"spaced   =   value with trailing space \n" +
+		"whitespace separated\n" +
+		"escaped\\=key\\:name = v\n" +
+		"key\\ with\\ spaces = v2\n" +
+		"continued = first, \\\n" +
+		"            second, \\\n" +
+		"            third\n" +
+		"unicode = caf\\u00e9 \\ud83d\\ude00\n" +
+		"escapes = tab\\tnewline\\nslash\\\\\n" +
+		"empty\n" +
+		"trailing = slash\\\\\n" +
+		"# not a \\\n" +
+		"last = one"
+
+	got, err := ParseProperties(content)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"colon":            "value",
+		"spaced":           "value with trailing space ",
+		"whitespace":       "separated",
+		"escaped=key:name": "v",
+		"key with spaces":  "v2",
+		"continued":        "first, second, third",
+		"unicode":          "café \U0001f600",
+		"escapes":          "tab\tnewline\nslash\\",
+		"empty":            "",
+		"trailing":         "slash\\",
+		"last":             "one",
+	}, got)
+
+	_, err = ParseProperties("bad = \\u12")
+	require.Error(t, err)
+
+	// a lone backslash ending the input continues onto nothing
+	got, err = ParseProperties("dangling = value\\")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"dangling": "value"}, got)
+
+	// and escapes nothing, once the line is joined
+	unescaped, err := unescapeProperty("value\\")
+	require.NoError(t, err)
+	require.Equal(t, "value", unescaped)
+}
@@ -0,0 +1,285 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+
+	"github.com/pkg/errors"
+)
+
+// published incremental chunks, like "nexus-maven-repository-index.42.gz",
+// are never rewritten within a chain, so cached copies need no revalidation
+var immutablePattern = regexp.MustCompile(`\.[0-9]+\.gz$`)
+
+// cacheMeta - the validators of a cached file, stored alongside it
+type cacheMeta struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+
+	// the chain ID of a cached properties file, or for an incremental
+	// chunk, of the cached properties file it was published under
+	ChainID string `json:"chainId,omitempty"`
+}
+
+type cachingResource struct {
+	// the URL associated with this Resource
+	URL string
+
+	// path of the cached copy of the URL's content
+	Path string
+
+	// logger instance
+	Logger *log.Logger
+
+	remote *httpResource
+	local  *localResource
+	body   *cachingBody
+}
+
+// NewCachingResource - an HTTP Resource whose content is stored under the
+// cache directory. Cached incremental chunks are served straight from disk
+// until the cached properties file lists another chain ID, while other files
+// are revalidated with If-None-Match/If-Modified-Since
+func NewCachingResource(logger *log.Logger, dir, uri string) (*cachingResource, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, errors.Wrapf(err, "NewCachingResource: invalid URI %q with cause", uri)
+	}
+
+	// path.Clean on a rooted path ensures the cache entry stays inside dir
+	return &cachingResource{
+		URL:    uri,
+		Path:   filepath.Join(dir, u.Host, filepath.FromSlash(path.Clean("/"+u.Path))),
+		Logger: logger,
+	}, nil
+}
+
+func (cr cachingResource) String() string {
+	return fmt.Sprintf("%T{%s}", cr, cr.URL)
+}
+
+// Reader -
+func (cr *cachingResource) Reader() (io.Reader, error) {
+	return cr.ReaderContext(context.Background())
+}
+
+// ReaderContext - serve the cached copy if it is immutable or still valid,
+// otherwise stream the remote content, caching it once read to completion
+func (cr *cachingResource) ReaderContext(ctx context.Context) (io.Reader, error) {
+	if cr.remote != nil || cr.local != nil {
+		return nil, errors.Errorf("CachingResource(%s): unexpected repeat call to Reader()", cr.URL)
+	}
+
+	meta, cached := cr.readMeta()
+	if cached && immutablePattern.MatchString(cr.URL) {
+		// chunk IDs restart when the chain rotates, so a chunk
+		// cached under another chain is a different chunk
+		chainID := cr.chainID()
+		if meta.ChainID == chainID {
+			return cr.serveLocal(ctx)
+		}
+		cr.Logger.Printf("CachingResource: discarding %s cached under chain %q, now %q", cr.Path, meta.ChainID, chainID)
+		cached = false
+	}
+
+	remote, err := NewHttpResource(cr.Logger, cr.URL)
+	if err != nil {
+		return nil, err
+	}
+	cr.remote = remote
+
+	if cached {
+		remote.header = http.Header{}
+		if meta.ETag != "" {
+			remote.header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			remote.header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	rdr, err := remote.ReaderContext(ctx)
+	if err != nil {
+		if se, ok := errors.Cause(err).(statusError); ok && cached && se.code == http.StatusNotModified {
+			return cr.serveLocal(ctx)
+		}
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cr.Path), 0o755); err != nil {
+		return nil, errors.Wrapf(err, "CachingResource: failed to create cache directory for %s with cause", cr.Path)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(cr.Path), filepath.Base(cr.Path)+".*.tmp")
+	if err != nil {
+		return nil, errors.Wrapf(err, "CachingResource: failed to create cache entry for %s with cause", cr.Path)
+	}
+
+	cr.body = &cachingBody{cr: cr, in: rdr, tmp: tmp}
+	return cr.body, nil
+}
+
+// Close - release the underlying Resource. Content that was
+// not read to completion is not added to the cache
+func (cr *cachingResource) Close() error {
+	switch {
+	case cr.local != nil:
+		return cr.local.Close()
+	case cr.remote != nil:
+		if cr.body != nil && cr.body.tmp != nil {
+			cr.body.discard(errors.New("closed before end of stream"))
+		}
+		return cr.remote.Close()
+	}
+
+	return errors.Errorf("CachingResource(%s): unexpected Close() call before Reader()", cr.URL)
+}
+
+func (cr *cachingResource) serveLocal(ctx context.Context) (io.Reader, error) {
+	if cr.remote != nil {
+		cr.remote.Close()
+		cr.remote = nil
+	}
+
+	local, err := NewLocalResource(cr.Logger, cr.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "CachingResource: failed to open cached copy of %s with cause", cr.URL)
+	}
+	cr.local = local
+	cr.Logger.Printf("CachingResource: serving %s from %s", cr.URL, cr.Path)
+
+	return local.ReaderContext(ctx)
+}
+
+func (cr *cachingResource) metaPath() string {
+	return cr.Path + ".meta"
+}
+
+func (cr *cachingResource) readMeta() (cacheMeta, bool) {
+	var meta cacheMeta
+
+	if _, err := os.Stat(cr.Path); err != nil {
+		return meta, false
+	}
+	content, err := os.ReadFile(cr.metaPath())
+	if err != nil {
+		return meta, false
+	}
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return meta, false
+	}
+
+	return meta, true
+}
+
+// commit - move the completed download, and its validators, into place
+func (cr *cachingResource) commit(tmpPath string) error {
+	meta := cacheMeta{ETag: cr.remote.etag, LastModified: cr.remote.lastModified}
+	switch {
+	case immutablePattern.MatchString(cr.URL):
+		meta.ChainID = cr.chainID()
+	case strings.HasSuffix(cr.URL, ".properties"):
+		meta.ChainID = propertiesChainID(tmpPath)
+	}
+	content, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	// drop stale validators first, so a failure can't pair them with new content
+	if err := os.Remove(cr.metaPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(tmpPath, cr.Path); err != nil {
+		return err
+	}
+
+	return os.WriteFile(cr.metaPath(), content, 0o644)
+}
+
+// chainID - the chain ID of the cached properties file of the index
+// an incremental chunk belongs to, or empty if it isn't cached
+func (cr *cachingResource) chainID() string {
+	props := cachingResource{Path: immutablePattern.ReplaceAllString(cr.Path, ".properties")}
+	meta, _ := props.readMeta()
+	return meta.ChainID
+}
+
+// propertiesChainID - the chain ID listed by the properties file at path,
+// or empty if none. Chain IDs are plain numbers, so need no unescaping
+func propertiesChainID(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			key, value, found = strings.Cut(strings.TrimSpace(line), ":")
+		}
+		if found && strings.TrimSpace(key) == data.PropertyChainID {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// cachingBody - tees the remote content into a temporary cache entry
+type cachingBody struct {
+	cr  *cachingResource
+	in  io.Reader
+	tmp *os.File
+}
+
+func (cb *cachingBody) Read(p []byte) (int, error) {
+	n, err := cb.in.Read(p)
+	if cb.tmp == nil {
+		return n, err
+	}
+
+	if n > 0 {
+		if _, wErr := cb.tmp.Write(p[:n]); wErr != nil {
+			cb.discard(wErr)
+			return n, err
+		}
+	}
+
+	switch {
+	case err == io.EOF:
+		tmpPath := cb.tmp.Name()
+		cErr := cb.tmp.Close()
+		cb.tmp = nil
+		if cErr == nil {
+			cErr = cb.cr.commit(tmpPath)
+		}
+		if cErr != nil {
+			os.Remove(tmpPath)
+			cb.cr.Logger.Printf("CachingResource: failed to cache %s: %s", cb.cr.URL, cErr)
+		}
+
+	case err != nil:
+		cb.discard(err)
+	}
+
+	return n, err
+}
+
+// discard - caching is best effort, and must not fail the read itself
+func (cb *cachingBody) discard(cause error) {
+	cb.cr.Logger.Printf("CachingResource: not caching %s: %s", cb.cr.URL, cause)
+	cb.tmp.Close()
+	os.Remove(cb.tmp.Name())
+	cb.tmp = nil
+}
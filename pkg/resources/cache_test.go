@@ -0,0 +1,126 @@
+package resources
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readCached(t *testing.T, dir, url string) string {
+	cr, err := NewCachingResource(log.Default(), dir, url)
+	require.NoError(t, err)
+	defer cr.Close()
+
+	rdr, err := cr.Reader()
+	require.NoError(t, err)
+	content, err := io.ReadAll(rdr)
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+func TestCachingResource(t *testing.T) {
+	var requests, notModified int32
+	var version atomic.Value
+	version.Store("v1")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		current := version.Load().(string)
+		etag := `"` + current + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(r.URL.Path + "@" + current))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	properties := srv.URL + "/.index/nexus-maven-repository-index.properties"
+	chunk := srv.URL + "/.index/nexus-maven-repository-index.7.gz"
+
+	// first reads populate the cache
+	require.Equal(t, "/.index/nexus-maven-repository-index.properties@v1", readCached(t, dir, properties))
+	require.Equal(t, "/.index/nexus-maven-repository-index.7.gz@v1", readCached(t, dir, chunk))
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// incremental chunks are immutable, and served without a request, while
+	// other files are revalidated and served from disk while unchanged
+	require.Equal(t, "/.index/nexus-maven-repository-index.7.gz@v1", readCached(t, dir, chunk))
+	require.Equal(t, "/.index/nexus-maven-repository-index.properties@v1", readCached(t, dir, properties))
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	require.Equal(t, int32(1), atomic.LoadInt32(&notModified))
+
+	// changed files are fetched, and replace the cached copy
+	version.Store("v2")
+	require.Equal(t, "/.index/nexus-maven-repository-index.properties@v2", readCached(t, dir, properties))
+	require.Equal(t, "/.index/nexus-maven-repository-index.properties@v2", readCached(t, dir, properties))
+	require.Equal(t, int32(5), atomic.LoadInt32(&requests))
+	require.Equal(t, int32(2), atomic.LoadInt32(&notModified))
+}
+
+func TestCachingResourceChainRotation(t *testing.T) {
+	var requests int32
+	var chain atomic.Value
+	chain.Store("1243533418968")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		current := chain.Load().(string)
+		w.Header().Set("ETag", `"`+current+`"`)
+		if strings.HasSuffix(r.URL.Path, ".properties") {
+			w.Write([]byte("nexus.index.id=central\nnexus.index.chain-id=" + current + "\n"))
+			return
+		}
+		w.Write([]byte(r.URL.Path + "@" + current))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	properties := srv.URL + "/.index/nexus-maven-repository-index.properties"
+	chunk := srv.URL + "/.index/nexus-maven-repository-index.1.gz"
+
+	readCached(t, dir, properties)
+	require.Equal(t, "/.index/nexus-maven-repository-index.1.gz@1243533418968", readCached(t, dir, chunk))
+	require.Equal(t, "/.index/nexus-maven-repository-index.1.gz@1243533418968", readCached(t, dir, chunk))
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// the index is rebuilt, restarting chunk IDs under a new chain, so
+	// the chunk cached under the old chain is fetched again
+	chain.Store("1243533419999")
+	require.Contains(t, readCached(t, dir, properties), "chain-id=1243533419999")
+	require.Equal(t, "/.index/nexus-maven-repository-index.1.gz@1243533419999", readCached(t, dir, chunk))
+	require.Equal(t, "/.index/nexus-maven-repository-index.1.gz@1243533419999", readCached(t, dir, chunk))
+	require.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestCachingResourcePartialRead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	url := srv.URL + "/nexus-maven-repository-index.1.gz"
+
+	// content that is not read to completion is not cached
+	cr, err := NewCachingResource(log.Default(), dir, url)
+	require.NoError(t, err)
+	rdr, err := cr.Reader()
+	require.NoError(t, err)
+	_, err = rdr.Read(make([]byte, 4))
+	require.NoError(t, err)
+	require.NoError(t, cr.Close())
+
+	cr, err = NewCachingResource(log.Default(), dir, url)
+	require.NoError(t, err)
+	_, cached := cr.readMeta()
+	require.False(t, cached)
+}
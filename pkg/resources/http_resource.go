@@ -1,19 +1,57 @@
 package resources
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/internal/utils"
 
 	"github.com/pkg/errors"
 )
 
 const UA = "Maven Index Reader Go"
 
+// RetryPolicy - governs how an httpResource retries transient failures,
+// both on the initial request and when the response body is cut short
+type RetryPolicy struct {
+	// attempts allowed after a failure, before giving up. The
+	// count is reset each time the download makes progress
+	MaxRetries int
+
+	// the first backoff interval, doubled after each failed attempt
+	InitialBackoff time.Duration
+
+	// the upper bound on any single backoff interval
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy - applied to each Resource built by NewHttpResource
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	wait := rp.InitialBackoff
+	for i := 0; i < attempt && wait < rp.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > rp.MaxBackoff {
+		wait = rp.MaxBackoff
+	}
+
+	return wait
+}
+
 type httpResource struct {
-	// provides access to the data represented by this Resource's URL
+	// provides access to the body of the current response, if any
 	reader io.ReadCloser
 
 	// the URL associated with this Resource
@@ -21,6 +59,22 @@ type httpResource struct {
 
 	// logger instance
 	Logger *log.Logger
+
+	// retry and backoff settings for transient failures
+	Retry RetryPolicy
+
+	// extra headers sent on the initial request only
+	header http.Header
+
+	// validators of the initial response
+	etag         string
+	lastModified string
+
+	ctx     context.Context
+	opened  bool
+	offset  int64  // bytes delivered to the caller so far
+	ifRange string // strong ETag or Last-Modified of the initial response
+	retries int    // failed attempts since the download last made progress
 }
 
 // NewHttpResource -
@@ -32,6 +86,7 @@ func NewHttpResource(logger *log.Logger, uri string) (*httpResource, error) {
 	return &httpResource{
 		Logger: logger,
 		URL:    uri,
+		Retry:  DefaultRetryPolicy,
 		reader: nil,
 	}, nil
 }
@@ -40,36 +95,206 @@ func (hr httpResource) String() string {
 	return fmt.Sprintf("%T{%s}", hr, hr.URL)
 }
 
-// Read -
+// Reader -
 func (hr *httpResource) Reader() (io.Reader, error) {
-	if hr.reader != nil {
+	return hr.ReaderContext(context.Background())
+}
+
+// ReaderContext - the in-flight request, and reads on the response body,
+// are cancelled with the context. Transient failures are retried with
+// exponential backoff and, if the body is cut short, the download resumes
+// from the last byte received using a Range request. Callers read one
+// continuous stream either way
+func (hr *httpResource) ReaderContext(ctx context.Context) (io.Reader, error) {
+	if hr.opened {
 		return nil, errors.New("HttpResource: unexpected repeat call to Reader()")
 	}
+	hr.opened = true
+	hr.ctx = ctx
+
+	if err := hr.fetch(nil); err != nil {
+		return nil, err
+	}
+
+	// this Resource's owner now bears responsibility to call Close
+	return &resumableBody{hr}, nil
+}
+
+// Close -
+func (hr *httpResource) Close() error {
+	if !hr.opened {
+		return errors.New("unexpected Close call on HttpResource's nil Reader")
+	}
 
-	req, err := http.NewRequest("GET", hr.URL, nil)
+	if hr.reader != nil {
+		hr.reader.Close()
+		hr.reader = nil
+	}
+	return nil
+}
+
+// fetch - (re)issue the request until it succeeds, or the cause of the
+// last failure is permanent or the retry budget is spent
+func (hr *httpResource) fetch(cause error) error {
+	for {
+		if cause != nil {
+			if !hr.transient(cause) || hr.retries >= hr.Retry.MaxRetries {
+				return errors.Wrapf(cause, "HttpResource: failed to fetch %s after %d retries with cause", hr.URL, hr.retries)
+			}
+
+			wait := hr.Retry.backoff(hr.retries)
+			hr.retries++
+			hr.Logger.Printf("HttpResource(%s): retry %d of %d at byte %d in %s after: %s",
+				hr.URL, hr.retries, hr.Retry.MaxRetries, hr.offset, wait, cause)
+			if err := utils.Sleep(hr.ctx, wait); err != nil {
+				return errors.Wrapf(err, "HttpResource: cancelled fetch of %s with cause", hr.URL)
+			}
+		}
+
+		body, err := hr.request()
+		if err == nil {
+			hr.reader = body
+			return nil
+		}
+		cause = err
+	}
+}
+
+// request - issue a single GET, resuming from the current offset if any
+func (hr *httpResource) request() (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(hr.ctx, "GET", hr.URL, nil)
 	if err != nil {
-		return nil, errors.Wrapf(err, "HttpResource: failed to build GET req to %s with cause", hr.URL)
+		return nil, permanentError{errors.Wrapf(err, "HttpResource: failed to build GET req to %s with cause", hr.URL)}
 	}
 
 	req.Header.Add("User-Agent", UA)
-	req.Header.Add("Accept-Encoding", "gzip")
+	// byte offsets must refer to the resource itself, not an encoding of it
+	req.Header.Add("Accept-Encoding", "identity")
+	if hr.offset == 0 {
+		for k, vs := range hr.header {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+	} else {
+		req.Header.Add("Range", fmt.Sprintf("bytes=%d-", hr.offset))
+		if hr.ifRange != "" {
+			req.Header.Add("If-Range", hr.ifRange)
+		}
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	// this Resource's owner now bears responsibility to call Close
-	hr.reader = resp.Body
-	return hr.reader, nil
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && hr.offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", hr.offset)) {
+			resp.Body.Close()
+			return nil, permanentError{errors.Errorf("HttpResource: GET %s resumed at unexpected range: %q", hr.URL, resp.Header.Get("Content-Range"))}
+		}
+
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		if hr.offset > 0 {
+			// the server ignored the Range, or the resource has changed
+			// since the download began, in which case we can't resume.
+			// without a validator, there's no telling which
+			if hr.ifRange == "" {
+				resp.Body.Close()
+				return nil, permanentError{errors.Errorf("HttpResource: %s can't be resumed without a validator", hr.URL)}
+			}
+			if hr.ifRange != validator(resp) {
+				resp.Body.Close()
+				return nil, permanentError{errors.Errorf("HttpResource: %s changed during download", hr.URL)}
+			}
+			if _, err := io.CopyN(io.Discard, resp.Body, hr.offset); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+		} else {
+			hr.ifRange = validator(resp)
+			hr.etag = resp.Header.Get("ETag")
+			hr.lastModified = resp.Header.Get("Last-Modified")
+		}
+
+	default:
+		// a missing chunk must not be mistaken for an error page's body
+		resp.Body.Close()
+		return nil, statusError{url: hr.URL, code: resp.StatusCode, status: resp.Status}
+	}
+
+	return resp.Body, nil
 }
 
-// Close -
-func (hr *httpResource) Close() error {
-	if hr.reader != nil {
+func (hr *httpResource) transient(err error) bool {
+	if hr.ctx.Err() != nil {
+		return false
+	}
+
+	switch cause := errors.Cause(err).(type) {
+	case permanentError:
+		return false
+	case statusError:
+		return cause.code >= 500 || cause.code == http.StatusTooManyRequests
+	default:
+		// dropped connections, timeouts, truncated bodies
+		return true
+	}
+}
+
+// resumableBody - the continuous stream handed to callers of ReaderContext
+type resumableBody struct {
+	hr *httpResource
+}
+
+func (rb *resumableBody) Read(p []byte) (int, error) {
+	hr := rb.hr
+	for {
+		if hr.reader == nil {
+			return 0, errors.Errorf("HttpResource: read on closed resource %s", hr.URL)
+		}
+
+		n, err := hr.reader.Read(p)
+		hr.offset += int64(n)
+		if n > 0 {
+			hr.retries = 0
+		}
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+
+		// the body was cut short: drop it, and resume on the next read
 		hr.reader.Close()
-		return nil
+		hr.reader = nil
+		if fErr := hr.fetch(err); fErr != nil {
+			return n, fErr
+		}
+		if n > 0 {
+			return n, nil
+		}
 	}
+}
+
+// validator - the value a later If-Range can use to detect that the
+// resource changed between requests. Weak ETags are not permitted
+func validator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+type statusError struct {
+	url    string
+	code   int
+	status string
+}
+
+func (se statusError) Error() string {
+	return fmt.Sprintf("HttpResource: GET %s failed with status: %s", se.url, se.status)
+}
 
-	return errors.New("unexpected Close call on HttpResource's nil Reader")
+type permanentError struct {
+	error
 }
@@ -0,0 +1,149 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     4 * time.Millisecond,
+}
+
+func testResource(t *testing.T, url string) *httpResource {
+	hr, err := NewHttpResource(log.Default(), url)
+	require.NoError(t, err)
+	hr.Retry = testRetryPolicy
+	return hr
+}
+
+func TestHttpResourceResumesTruncatedBody(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	modified := time.Unix(1243533418, 0)
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "identity", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("ETag", `"v1"`)
+
+		// the first response is cut off partway through the body
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/3])
+			return
+		}
+
+		require.NotEmpty(t, r.Header.Get("Range"))
+		require.Equal(t, `"v1"`, r.Header.Get("If-Range"))
+		http.ServeContent(w, r, "index.gz", modified, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	hr := testResource(t, srv.URL)
+	rdr, err := hr.ReaderContext(context.Background())
+	require.NoError(t, err)
+
+	got, err := io.ReadAll(rdr)
+	require.NoError(t, err)
+	require.Equal(t, content, got)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.NoError(t, hr.Close())
+}
+
+func TestHttpResourceWithoutValidator(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+
+	// no ETag or Last-Modified, and a full body in place of a range,
+	// so the content served next may not be what was already read
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Write(content[:len(content)/3])
+			return
+		}
+
+		require.Empty(t, r.Header.Get("If-Range"))
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	hr := testResource(t, srv.URL)
+	rdr, err := hr.ReaderContext(context.Background())
+	require.NoError(t, err)
+
+	_, err = io.ReadAll(rdr)
+	require.ErrorContains(t, err, "without a validator")
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	require.NoError(t, hr.Close())
+}
+
+func TestHttpResourceRetriesTransientStatus(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	hr := testResource(t, srv.URL)
+	rdr, err := hr.Reader()
+	require.NoError(t, err)
+	got, err := io.ReadAll(rdr)
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(got))
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	require.NoError(t, hr.Close())
+}
+
+func TestHttpResourceFailures(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	// missing resources are not retried
+	hr := testResource(t, srv.URL+"/missing")
+	_, err := hr.Reader()
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	require.NoError(t, hr.Close())
+
+	// transient failures give up once the retry budget is spent
+	atomic.StoreInt32(&requests, 0)
+	hr = testResource(t, srv.URL+"/flaky")
+	_, err = hr.Reader()
+	require.Error(t, err)
+	require.Equal(t, int32(testRetryPolicy.MaxRetries+1), atomic.LoadInt32(&requests))
+	require.NoError(t, hr.Close())
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	rp := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	require.Equal(t, time.Second, rp.backoff(0))
+	require.Equal(t, 2*time.Second, rp.backoff(1))
+	require.Equal(t, 4*time.Second, rp.backoff(2))
+	require.Equal(t, 5*time.Second, rp.backoff(3))
+	require.Equal(t, 5*time.Second, rp.backoff(30))
+}
@@ -2,10 +2,13 @@ package resources
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"log"
 	"os"
 
+	"github.com/elireisman/maven-index-reader-go/internal/utils"
+
 	"github.com/pkg/errors"
 )
 
@@ -20,9 +23,12 @@ type localResource struct {
 
 func NewLocalResource(l *log.Logger, path string) (*localResource, error) {
 	info, err := os.Stat(path)
-	if os.IsNotExist(err) || info.IsDir() {
+	if err != nil {
 		return nil, errors.Wrapf(err, "NewLocalResource: failed to stat expected file at %s with cause", path)
 	}
+	if info.IsDir() {
+		return nil, errors.Errorf("NewLocalResource: expected file at %s, found directory", path)
+	}
 
 	return &localResource{
 		Logger: l,
@@ -31,7 +37,12 @@ func NewLocalResource(l *log.Logger, path string) (*localResource, error) {
 	}, nil
 }
 
-func (lr localResource) Reader() (io.Reader, error) {
+func (lr *localResource) Reader() (io.Reader, error) {
+	return lr.ReaderContext(context.Background())
+}
+
+// ReaderContext - reads fail once the context is done
+func (lr *localResource) ReaderContext(ctx context.Context) (io.Reader, error) {
 	if lr.reader != nil {
 		return nil, errors.Errorf("LocalResource(%s): unexpected Reader() call on non-nil io.ReadCloser", lr.Path)
 	}
@@ -42,12 +53,12 @@ func (lr localResource) Reader() (io.Reader, error) {
 	}
 
 	lr.reader = f
-	buf := bufio.NewReader(f)
+	buf := bufio.NewReader(utils.NewContextReader(ctx, f))
 
 	return buf, nil
 }
 
-func (lr localResource) Close() error {
+func (lr *localResource) Close() error {
 	if lr.reader == nil {
 		return errors.Errorf("LocalResource(%s): unexpected Close() call on nil io.ReadCloser", lr.Path)
 	}
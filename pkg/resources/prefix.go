@@ -0,0 +1,66 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+
+	"github.com/pkg/errors"
+)
+
+// ReadPrefix - obtain at most the first n bytes of the target, as when only
+// a file's header is of interest. Over HTTP, only that Range is requested,
+// and cached copies (config.Source.Cache) are used where available. A prefix
+// can't be checked against published checksums or signatures, so
+// config.Index.Verify does not apply
+func ReadPrefix(ctx context.Context, logger *log.Logger, cfg config.Index, target string, n int64) ([]byte, error) {
+	var resource Resource
+	var err error
+
+	switch cfg.Source.Type {
+	case config.HTTP:
+		if cfg.Source.Cache != "" {
+			cached, cErr := NewCachingResource(logger, cfg.Source.Cache, target)
+			if cErr != nil {
+				return nil, cErr
+			}
+			if _, ok := cached.readMeta(); ok {
+				resource, err = NewLocalResource(logger, cached.Path)
+				break
+			}
+		}
+
+		var remote *httpResource
+		remote, err = NewHttpResource(logger, target)
+		if err == nil {
+			// servers ignoring the Range send the full body,
+			// which is cut off once n bytes have been read
+			remote.header = http.Header{}
+			remote.header.Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+			resource = remote
+		}
+
+	default:
+		resource, err = fromSource(logger, cfg, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resource.Close()
+
+	rdr, err := resource.ReaderContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(rdr, n))
+	if err != nil {
+		return nil, errors.Wrapf(err, "ReadPrefix: failed to read first %d bytes of %s with cause", n, target)
+	}
+
+	return prefix, nil
+}
@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"context"
 	"io"
 	"log"
 
@@ -14,12 +15,27 @@ type Resource interface {
 	// Obtain a Reader on the given Resource, or an error
 	Reader() (io.Reader, error)
 
+	// Obtain a Reader on the given Resource bound to the supplied
+	// context, or an error. Reads fail once the context is done
+	ReaderContext(ctx context.Context) (io.Reader, error)
+
 	// Close the resource
 	Close() error
 }
 
-// resolve a Resource from caller-supplied config.Index
+// resolve a Resource from caller-supplied config.Index. If the config
+// enables verification (config.Index.Verify), the Resource checks its
+// content as it is read, failing with a *VerificationError at end of stream
 func FromConfig(logger *log.Logger, cfg config.Index, target string) (Resource, error) {
+	resource, err := fromSource(logger, cfg, target)
+	if err != nil || (!cfg.Verify.Checksums && cfg.Verify.Keyring == "") {
+		return resource, err
+	}
+
+	return NewVerifyingResource(logger, cfg, target, resource)
+}
+
+func fromSource(logger *log.Logger, cfg config.Index, target string) (Resource, error) {
 	var resource Resource
 	var err error
 
@@ -27,7 +43,11 @@ func FromConfig(logger *log.Logger, cfg config.Index, target string) (Resource,
 	case config.Local:
 		resource, err = NewLocalResource(logger, target)
 	case config.HTTP:
-		resource, err = NewHttpResource(logger, target)
+		if cfg.Source.Cache != "" {
+			resource, err = NewCachingResource(logger, cfg.Source.Cache, target)
+		} else {
+			resource, err = NewHttpResource(logger, target)
+		}
 	default:
 		err = errors.Errorf("ConfigureResource: invalid config.Index.Source.Type for target %s, got: %d", target, cfg.Source.Type)
 	}
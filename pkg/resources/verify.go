@@ -0,0 +1,332 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/pkg/errors"
+)
+
+// the checks a VerificationError may report
+const (
+	CheckSHA1      = "sha1"
+	CheckMD5       = "md5"
+	CheckSignature = "asc"
+)
+
+// VerificationError - the content of a Resource failed a checksum or
+// signature check, or the sibling file needed to check it is missing
+type VerificationError struct {
+	// the verified Resource's target
+	Target string
+
+	// one of CheckSHA1, CheckMD5 or CheckSignature
+	Check string
+
+	// for checksums, the published and computed digests
+	Expected string
+	Actual   string
+
+	// the underlying failure, if any
+	Err error
+}
+
+func (ve *VerificationError) Error() string {
+	switch {
+	case ve.Err != nil:
+		return fmt.Sprintf("Verification(%s): %s check failed: %s", ve.Target, ve.Check, ve.Err)
+	default:
+		return fmt.Sprintf("Verification(%s): %s check failed: expected %s, got: %s", ve.Target, ve.Check, ve.Expected, ve.Actual)
+	}
+}
+
+func (ve *VerificationError) Unwrap() error {
+	return ve.Err
+}
+
+type verifyingResource struct {
+	// the verified Resource's target
+	Target string
+
+	// logger instance
+	Logger *log.Logger
+
+	cfg     config.Index
+	inner   Resource
+	keyring openpgp.EntityList
+	body    *verifyingBody
+}
+
+// NewVerifyingResource - wraps the Resource for target, checking its content
+// against the published ".sha1"/".md5" checksum and ".asc" signature siblings
+// as enabled by config.Index.Verify. Content is hashed as it streams through,
+// and the checks are made once the caller reads to the end of the stream
+func NewVerifyingResource(logger *log.Logger, cfg config.Index, target string, inner Resource) (*verifyingResource, error) {
+	vr := &verifyingResource{
+		Target: target,
+		Logger: logger,
+		cfg:    cfg,
+		inner:  inner,
+	}
+
+	if cfg.Verify.Keyring != "" {
+		keyring, err := readKeyring(cfg.Verify.Keyring)
+		if err != nil {
+			inner.Close()
+			return nil, errors.Wrapf(err, "NewVerifyingResource: failed to read keyring %s with cause", cfg.Verify.Keyring)
+		}
+		vr.keyring = keyring
+	}
+
+	return vr, nil
+}
+
+func (vr verifyingResource) String() string {
+	return fmt.Sprintf("%T{%s}", vr, vr.Target)
+}
+
+// Reader -
+func (vr *verifyingResource) Reader() (io.Reader, error) {
+	return vr.ReaderContext(context.Background())
+}
+
+// ReaderContext - the stream ends with a *VerificationError
+// in place of io.EOF if the content fails verification
+func (vr *verifyingResource) ReaderContext(ctx context.Context) (io.Reader, error) {
+	if vr.body != nil {
+		return nil, errors.Errorf("VerifyingResource(%s): unexpected repeat call to Reader()", vr.Target)
+	}
+
+	rdr, err := vr.inner.ReaderContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vr.body = &verifyingBody{
+		vr:     vr,
+		ctx:    ctx,
+		in:     rdr,
+		hashes: map[string]hash.Hash{},
+	}
+	if vr.cfg.Verify.Checksums {
+		vr.body.hashes[CheckSHA1] = sha1.New()
+		vr.body.hashes[CheckMD5] = md5.New()
+	}
+	if vr.keyring != nil {
+		vr.body.startSignatureCheck()
+	}
+
+	return vr.body, nil
+}
+
+// Close -
+func (vr *verifyingResource) Close() error {
+	if vr.body != nil {
+		vr.body.stopSignatureCheck(errors.New("closed before end of stream"))
+	}
+
+	return vr.inner.Close()
+}
+
+// sibling - the content of the target's sibling with the given extension,
+// or nil if the sibling has not been published
+func (vr *verifyingResource) sibling(ctx context.Context, ext string) ([]byte, error) {
+	target := vr.Target + "." + ext
+	rsc, err := fromSource(vr.Logger, vr.cfg, target)
+	if err != nil {
+		if IsMissing(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rsc.Close()
+
+	rdr, err := rsc.ReaderContext(ctx)
+	if err != nil {
+		if IsMissing(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// checksums and signatures are tiny; anything larger is not one
+	return io.ReadAll(io.LimitReader(rdr, 64*1024))
+}
+
+// verifyingBody - hashes the content as the caller reads it
+type verifyingBody struct {
+	vr     *verifyingResource
+	ctx    context.Context
+	in     io.Reader
+	hashes map[string]hash.Hash
+
+	// content is piped to the signature check as it is read
+	sigIn     *io.PipeWriter
+	sigResult chan error
+	sigErr    error
+
+	verified bool
+	err      error
+}
+
+func (vb *verifyingBody) Read(p []byte) (int, error) {
+	if vb.verified {
+		return 0, vb.err
+	}
+
+	n, err := vb.in.Read(p)
+	if n > 0 {
+		for _, h := range vb.hashes {
+			h.Write(p[:n])
+		}
+		if vb.sigIn != nil {
+			if _, wErr := vb.sigIn.Write(p[:n]); wErr != nil {
+				// the check gave up early, and will report why
+				vb.sigIn = nil
+			}
+		}
+	}
+
+	if err == io.EOF {
+		vb.verified = true
+		if vErr := vb.verify(); vErr != nil {
+			vb.err = vErr
+			return n, vErr
+		}
+		vb.err = io.EOF
+	}
+
+	return n, err
+}
+
+// verify - compare the hashed content against each published check
+func (vb *verifyingBody) verify() error {
+	target := vb.vr.Target
+
+	if len(vb.hashes) > 0 {
+		checked := 0
+		for _, check := range []string{CheckSHA1, CheckMD5} {
+			published, err := vb.vr.sibling(vb.ctx, check)
+			if err != nil {
+				return &VerificationError{Target: target, Check: check, Err: err}
+			}
+			if published == nil {
+				continue
+			}
+
+			// checksum files may list the file name after the digest
+			fields := strings.Fields(string(published))
+			expected := ""
+			if len(fields) > 0 {
+				expected = strings.ToLower(fields[0])
+			}
+			actual := hex.EncodeToString(vb.hashes[check].Sum(nil))
+			if expected != actual {
+				return &VerificationError{Target: target, Check: check, Expected: expected, Actual: actual}
+			}
+			checked++
+		}
+		if checked == 0 {
+			return &VerificationError{Target: target, Check: CheckSHA1, Err: errors.New("no .sha1 or .md5 checksum published")}
+		}
+	}
+
+	if vb.sigResult != nil {
+		vb.stopSignatureCheck(nil)
+		if vb.sigErr != nil {
+			return &VerificationError{Target: target, Check: CheckSignature, Err: vb.sigErr}
+		}
+	}
+
+	vb.vr.Logger.Printf("Verification(%s): content verified", target)
+	return nil
+}
+
+// startSignatureCheck - verify the detached signature in the background,
+// as the content is piped through
+func (vb *verifyingBody) startSignatureCheck() {
+	pr, pw := io.Pipe()
+	vb.sigIn = pw
+	vb.sigResult = make(chan error, 1)
+
+	go func() {
+		err := vb.checkSignature(pr)
+		// unblock, and fail, any further writes if the check gave up early
+		pr.CloseWithError(errors.New("signature check complete"))
+		vb.sigResult <- err
+	}()
+}
+
+func (vb *verifyingBody) checkSignature(signed io.Reader) error {
+	signature, err := vb.vr.sibling(vb.ctx, CheckSignature)
+	if err != nil {
+		return err
+	}
+	if signature == nil {
+		return errors.New("no .asc signature published")
+	}
+
+	signer, err := openpgp.CheckArmoredDetachedSignature(vb.vr.keyring, signed, bytes.NewReader(signature), nil)
+	if err != nil {
+		return err
+	}
+
+	for name := range signer.Identities {
+		vb.vr.Logger.Printf("Verification(%s): signed by %s", vb.vr.Target, name)
+	}
+	return nil
+}
+
+// stopSignatureCheck - end the piped content, and wait for the check's result
+func (vb *verifyingBody) stopSignatureCheck(cause error) {
+	if vb.sigResult == nil {
+		return
+	}
+
+	if vb.sigIn != nil {
+		if cause != nil {
+			vb.sigIn.CloseWithError(cause)
+		} else {
+			vb.sigIn.Close()
+		}
+	}
+	vb.sigErr = <-vb.sigResult
+	vb.sigIn = nil
+	vb.sigResult = nil
+}
+
+// IsMissing - whether the error reports a Resource that does not exist
+func IsMissing(err error) bool {
+	cause := errors.Cause(err)
+	if se, ok := cause.(statusError); ok {
+		return se.code == http.StatusNotFound || se.code == http.StatusGone
+	}
+
+	return os.IsNotExist(cause)
+}
+
+// readKeyring - load an armored or binary OpenPGP keyring file
+func readKeyring(path string) (openpgp.EntityList, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(content)); err == nil {
+		return keyring, nil
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(content))
+}
@@ -0,0 +1,125 @@
+package resources
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyring(t *testing.T, path string) *openpgp.Entity {
+	entity, err := openpgp.NewEntity("Index Publisher", "", "index@example.org", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	return entity
+}
+
+func writeSignature(t *testing.T, signer *openpgp.Entity, content []byte, path string) {
+	var buf bytes.Buffer
+	require.NoError(t, openpgp.ArmoredDetachSign(&buf, signer, bytes.NewReader(content), nil))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+}
+
+func readVerified(cfg config.Index, target string) ([]byte, error) {
+	rsc, err := FromConfig(log.Default(), cfg, target)
+	if err != nil {
+		return nil, err
+	}
+	defer rsc.Close()
+
+	rdr, err := rsc.Reader()
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(rdr)
+}
+
+func TestVerifyingResource(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("nexus.index.id=central\n")
+	target := filepath.Join(dir, "nexus-maven-repository-index.properties")
+	require.NoError(t, os.WriteFile(target, content, 0o644))
+
+	sha1Sum := sha1.Sum(content)
+	md5Sum := md5.Sum(content)
+	require.NoError(t, os.WriteFile(target+".sha1", []byte(hex.EncodeToString(sha1Sum[:])+"  nexus-maven-repository-index.properties\n"), 0o644))
+	require.NoError(t, os.WriteFile(target+".md5", []byte(hex.EncodeToString(md5Sum[:])), 0o644))
+
+	keyring := filepath.Join(dir, "keyring.asc")
+	signer := writeKeyring(t, keyring)
+	writeSignature(t, signer, content, target+".asc")
+
+	cfg := config.Index{
+		Source: config.Source{Type: config.Local},
+		Verify: config.Verify{Checksums: true, Keyring: keyring},
+	}
+
+	// verified content reads as normal
+	got, err := readVerified(cfg, target)
+	require.NoError(t, err)
+	require.Equal(t, content, got)
+
+	// a corrupted checksum fails verification
+	require.NoError(t, os.WriteFile(target+".md5", []byte("d41d8cd98f00b204e9800998ecf8427e"), 0o644))
+	_, err = readVerified(cfg, target)
+	var verr *VerificationError
+	require.True(t, errors.As(err, &verr), "%s", err)
+	require.Equal(t, CheckMD5, verr.Check)
+	require.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", verr.Expected)
+	require.Equal(t, hex.EncodeToString(md5Sum[:]), verr.Actual)
+
+	// missing checksums are tolerated, so long as one is published
+	require.NoError(t, os.Remove(target+".md5"))
+	_, err = readVerified(cfg, target)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(target+".sha1"))
+	_, err = readVerified(cfg, target)
+	require.True(t, errors.As(err, &verr), "%s", err)
+	require.Equal(t, CheckSHA1, verr.Check)
+
+	// a signature from a key outside the keyring fails verification
+	cfg.Verify.Checksums = false
+	other, err := openpgp.NewEntity("Someone Else", "", "else@example.org", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	require.NoError(t, err)
+	writeSignature(t, other, content, target+".asc")
+	_, err = readVerified(cfg, target)
+	require.True(t, errors.As(err, &verr), "%s", err)
+	require.Equal(t, CheckSignature, verr.Check)
+	require.Error(t, verr.Err)
+
+	// as does tampered content
+	writeSignature(t, signer, content, target+".asc")
+	require.NoError(t, os.WriteFile(target, []byte("nexus.index.id=evil\n"), 0o644))
+	_, err = readVerified(cfg, target)
+	require.True(t, errors.As(err, &verr), "%s", err)
+	require.Equal(t, CheckSignature, verr.Check)
+
+	// closing before the end of the stream skips verification
+	rsc, err := FromConfig(log.Default(), cfg, target)
+	require.NoError(t, err)
+	rdr, err := rsc.Reader()
+	require.NoError(t, err)
+	_, err = rdr.Read(make([]byte, 4))
+	require.NoError(t, err)
+	require.NoError(t, rsc.Close())
+}
@@ -0,0 +1,152 @@
+package writers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/internal/utils"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+
+	"github.com/pkg/errors"
+)
+
+// ChunkVersion - the only index chunk format version in the wild
+const ChunkVersion = 1
+
+type Chunk struct {
+	target    string
+	timestamp time.Time
+	logger    *log.Logger
+	input     <-chan data.Record
+}
+
+// NewChunk - caller supplies the local file path of the chunk to write,
+// the chunk timestamp to record in its header, and the channel of
+// data.Records to publish. The caller is responsible for closing the channel
+func NewChunk(l *log.Logger, in <-chan data.Record, t string, ts time.Time) Chunk {
+	return Chunk{
+		target:    t,
+		timestamp: ts,
+		logger:    l,
+		input:     in,
+	}
+}
+
+// Write - create the target file and populate it with the data.Records
+// consumed from the input channel, until the channel is closed
+func (cw Chunk) Write() error {
+	path := filepath.Dir(cw.target)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return errors.Wrapf(err, "Chunk: failed to create output directory at %s with cause", path)
+	}
+
+	f, err := os.Create(cw.target)
+	if err != nil {
+		return errors.Wrapf(err, "Chunk: failed to create output file at %s with cause", cw.target)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := cw.Encode(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrapf(err, "Chunk: failed to flush output file %s with cause", cw.target)
+	}
+
+	return f.Close()
+}
+
+// Encode - write the GZIP compressed chunk to the supplied io.Writer in
+// the format consumed by readers.Chunk and the Java index reader
+// https://github.com/apache/maven-indexer/blob/31052fdeebc8a9f845eb18cd4c13669b316b3e29/indexer-reader/src/main/java/org/apache/maven/index/reader/ChunkWriter.java
+func (cw Chunk) Encode(w io.Writer) error {
+	gzWtr := gzip.NewWriter(w)
+
+	if err := utils.WriteByte(gzWtr, ChunkVersion); err != nil {
+		return errors.Wrapf(err, "Chunk(%s): failed to write chunk version with cause", cw.target)
+	}
+
+	// the Java writer records a missing timestamp as -1
+	millis := int64(-1)
+	if !cw.timestamp.IsZero() {
+		millis = cw.timestamp.UnixMilli()
+	}
+	if err := utils.WriteInt64(gzWtr, millis); err != nil {
+		return errors.Wrapf(err, "Chunk(%s): failed to write chunk timestamp with cause", cw.target)
+	}
+
+	count := 0
+	for record := range cw.input {
+		count++
+
+		// re-emit the raw fields a Record was parsed from as-is, preserving
+		// field order, flags and custom fields. Otherwise, compact the Record
+		rawRecord := record.Raw()
+		if len(rawRecord) == 0 {
+			compacted, err := data.CompactRecord(record)
+			if err != nil {
+				return errors.Wrapf(err, "Chunk(%s): failed to compact record %d with cause", cw.target, count)
+			}
+			rawRecord = compactedFields(compacted)
+		}
+
+		if err := writeRecord(gzWtr, rawRecord); err != nil {
+			return errors.Wrapf(err, "Chunk(%s): failed to write record %d with cause", cw.target, count)
+		}
+	}
+
+	if err := gzWtr.Close(); err != nil {
+		return errors.Wrapf(err, "Chunk(%s): failed to finalize GZIP stream with cause", cw.target)
+	}
+
+	cw.logger.Printf("Chunk: successfully wrote %d records to %s", count, cw.target)
+	return nil
+}
+
+func writeRecord(w io.Writer, rawRecord []data.RawField) error {
+	if err := utils.WriteInt32(w, int32(len(rawRecord))); err != nil {
+		return errors.Wrap(err, "failed to write field count with cause")
+	}
+
+	for _, field := range rawRecord {
+		if err := utils.WriteByte(w, byte(field.Flags)); err != nil {
+			return errors.Wrapf(err, "failed to write flags for field %s with cause", field.Key)
+		}
+
+		// a Record's *key* is limited to a 2 byte size field
+		if err := utils.WriteString(w, field.Key); err != nil {
+			return errors.Wrapf(err, "failed to write key for field %s with cause", field.Key)
+		}
+
+		// a Record's *value* can be larger; the size field is 4 bytes
+		if err := utils.WriteLargeString(w, field.Value); err != nil {
+			return errors.Wrapf(err, "failed to write value for field %s with cause", field.Key)
+		}
+	}
+
+	return nil
+}
+
+// resolve raw fields in stable key order, keeping chunk output
+// reproducible, with the flags the Java ChunkWriter assigns
+func compactedFields(rawRecord map[string]string) []data.RawField {
+	rawKeys := make([]string, 0, len(rawRecord))
+	for key := range rawRecord {
+		rawKeys = append(rawKeys, key)
+	}
+	sort.Strings(rawKeys)
+
+	out := make([]data.RawField, 0, len(rawKeys))
+	for _, key := range rawKeys {
+		out = append(out, data.RawField{Key: key, Value: rawRecord[key], Flags: data.DefaultFieldFlags(key)})
+	}
+
+	return out
+}
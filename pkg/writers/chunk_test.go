@@ -0,0 +1,147 @@
+package writers
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/readers"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig(base string) config.Index {
+	return config.Index{
+		Meta: config.Meta{
+			ID:      "apache-snapshots-local",
+			ChainID: "1243533418968",
+			File:    "nexus-maven-repository-index",
+		},
+		Source: config.Source{
+			Base: base,
+			Type: config.Local,
+		},
+		Mode: config.Mode{
+			Type: config.All,
+		},
+		Output: config.Output{
+			Format: config.Log,
+		},
+	}
+}
+
+func readAll(t *testing.T, cfg config.Index, target string) []data.Record {
+	records := make(chan data.Record, 64)
+	chunk := readers.NewChunk(log.Default(), records, cfg, target, nil)
+
+	err := chunk.Read()
+	require.True(t, errors.Cause(err) == io.EOF, "(%T) %s", err, err)
+	close(records)
+
+	var out []data.Record
+	for record := range records {
+		out = append(out, record)
+	}
+	return out
+}
+
+func TestChunkRoundTrip(t *testing.T) {
+	logger := log.Default()
+
+	srcCfg := testConfig("../readers/testdata/")
+	expected := readAll(t, srcCfg, srcCfg.ResolveTarget(".gz"))
+	require.Len(t, expected, 5)
+
+	dstCfg := testConfig(t.TempDir() + string(filepath.Separator))
+	target := dstCfg.ResolveTarget(".gz")
+
+	records := make(chan data.Record, len(expected))
+	for _, record := range expected {
+		records <- record
+	}
+	close(records)
+
+	chunk := NewChunk(logger, records, target, time.UnixMilli(1243533418968))
+	require.NoError(t, chunk.Write())
+
+	got := readAll(t, dstCfg, target)
+	require.Equal(t, len(expected), len(got))
+	for ndx := range expected {
+		require.Equal(t, expected[ndx].Type(), got[ndx].Type())
+		require.Equal(t, expected[ndx].Payload(), got[ndx].Payload())
+		require.Equal(t, expected[ndx].Raw(), got[ndx].Raw())
+	}
+}
+
+func TestChunkRoundTripUnicode(t *testing.T) {
+	logger := log.Default()
+
+	raw := map[string]string{
+		data.UInfoKey:          "org.example|widget|1.0|NA|jar",
+		data.InfoKey:           "jar|1243533415343|1024|1|0|1|jar",
+		data.RecordModifiedKey: "1243533417953",
+		data.NameKey:           "Widget €",
+		data.DescriptionKey:    "Emoji \U0001f600 and accents éý",
+		data.SHA1Key:           "38bb5a445e9aa5a38581743ede58f46c0f1ce321",
+	}
+	expected, err := data.NewRecord(logger, raw)
+	require.NoError(t, err)
+
+	cfg := testConfig(t.TempDir() + string(filepath.Separator))
+	target := cfg.ResolveTarget(".1.gz")
+
+	records := make(chan data.Record, 1)
+	records <- expected
+	close(records)
+	require.NoError(t, NewChunk(logger, records, target, time.Now()).Write())
+
+	got := readAll(t, cfg, target)
+	require.Len(t, got, 1)
+	require.Equal(t, data.ArtifactAdd, got[0].Type())
+	require.Equal(t, expected.Payload(), got[0].Payload())
+	require.Equal(t, "Widget €", got[0].Get("name"))
+	require.Equal(t, int64(1024), got[0].Get("fileSize"))
+}
+
+func TestChunkRoundTripRawFields(t *testing.T) {
+	logger := log.Default()
+
+	fields := []data.RawField{
+		{Key: data.UInfoKey, Value: "org.example|widget|1.0|NA|jar", Flags: data.FlagIndexed | data.FlagStored},
+		{Key: data.InfoKey, Value: "jar|1243533415343|1024|1|0|1|jar", Flags: data.FlagStored},
+		{Key: "x-custom", Value: "from a custom IndexCreator", Flags: data.FlagStored | data.FlagCompressed},
+	}
+	expected, err := data.NewRecordFromFields(logger, fields)
+	require.NoError(t, err)
+	require.Nil(t, expected.Get("x-custom"))
+
+	cfg := testConfig(t.TempDir() + string(filepath.Separator))
+	target := cfg.ResolveTarget(".1.gz")
+
+	records := make(chan data.Record, 1)
+	records <- expected
+	close(records)
+	require.NoError(t, NewChunk(logger, records, target, time.Now()).Write())
+
+	got := readAll(t, cfg, target)
+	require.Len(t, got, 1)
+	require.Equal(t, fields, got[0].Raw())
+	require.Equal(t, expected.Payload(), got[0].Payload())
+
+	flags, found := got[0].Flags("x-custom")
+	require.True(t, found)
+	require.True(t, flags.Compressed())
+	require.False(t, flags.Indexed())
+
+	value, found := got[0].RawValue("x-custom")
+	require.True(t, found)
+	require.Equal(t, "from a custom IndexCreator", value)
+
+	_, found = got[0].Flags("missing")
+	require.False(t, found)
+}
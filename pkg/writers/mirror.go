@@ -0,0 +1,147 @@
+package writers
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/readers"
+	"github.com/elireisman/maven-index-reader-go/pkg/resources"
+
+	"github.com/pkg/errors"
+)
+
+// Mirror - replicates the index at config.Index.Source into a local
+// directory with the same layout, so that a config.Local source with
+// the directory as its base can read the mirror directly
+type Mirror struct {
+	cfg    config.Index
+	logger *log.Logger
+	dest   string
+}
+
+func NewMirror(l *log.Logger, c config.Index, dest string) Mirror {
+	return Mirror{
+		cfg:    c,
+		logger: l,
+		dest:   dest,
+	}
+}
+
+// Sync - download the full chunk and every incremental chunk listed in the
+// index properties, then the properties file itself, so the mirror never
+// lists a chunk it lacks. Files are copied verbatim. Incremental chunks are
+// immutable, so those already mirrored from the same chain are not fetched
+// again. Returns the names of the files downloaded.
+func (m Mirror) Sync(ctx context.Context) ([]string, error) {
+	// a rotated chain is handled below, like any other chain change
+	props, err := readers.NewIndex(m.logger, nil, m.cfg).Properties(ctx)
+	if err != nil && !readers.IsChainRotated(err) {
+		return nil, errors.Wrap(err, "from Mirror#Sync")
+	}
+
+	if err := os.MkdirAll(m.dest, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "Mirror: failed to create destination %s with cause", m.dest)
+	}
+
+	// a rebuilt index restarts its chunk IDs, invalidating mirrored chunks
+	chainID, _ := props.GetAsString(data.PropertyChainID)
+	sameChain := m.mirroredChainID() == chainID
+	if !sameChain {
+		if err := os.Remove(m.destPath(".properties")); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "Mirror: failed to remove stale properties from %s with cause", m.dest)
+		}
+	}
+
+	listed, err := props.Incrementals()
+	if err != nil {
+		return nil, errors.Wrap(err, "from Mirror#Sync")
+	}
+
+	suffixes := []string{".gz"}
+	for _, chunkID := range listed {
+		suffix := "." + strconv.Itoa(chunkID) + ".gz"
+		if _, err := os.Stat(m.destPath(suffix)); err == nil && sameChain {
+			if m.cfg.Verbose {
+				m.logger.Printf("Mirror: skipping previously mirrored chunk %s", m.destPath(suffix))
+			}
+			continue
+		}
+		suffixes = append(suffixes, suffix)
+	}
+	suffixes = append(suffixes, ".properties")
+
+	var out []string
+	for _, suffix := range suffixes {
+		if err := m.download(ctx, suffix); err != nil {
+			return out, errors.Wrap(err, "from Mirror#Sync")
+		}
+		out = append(out, m.destPath(suffix))
+	}
+
+	m.logger.Printf("Mirror: successfully mirrored %d files to %s", len(out), m.dest)
+	return out, nil
+}
+
+func (m Mirror) destPath(suffix string) string {
+	return filepath.Join(m.dest, m.cfg.Meta.File+suffix)
+}
+
+// mirroredChainID - the chain ID of the existing mirror, if any
+func (m Mirror) mirroredChainID() string {
+	content, err := os.ReadFile(m.destPath(".properties"))
+	if err != nil {
+		return ""
+	}
+
+	props, err := readers.ParseProperties(string(content))
+	if err != nil {
+		return ""
+	}
+
+	return props[data.PropertyChainID]
+}
+
+// download - copy the remote file verbatim, moving it into place once complete
+func (m Mirror) download(ctx context.Context, suffix string) error {
+	target := m.cfg.ResolveTarget(suffix)
+	rsc, err := resources.FromConfig(m.logger, m.cfg, target)
+	if err != nil {
+		return errors.Wrapf(err, "Mirror: failed to resolve resource %s with cause", target)
+	}
+	defer rsc.Close()
+
+	rdr, err := rsc.ReaderContext(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "Mirror: failed to obtain data stream from %s with cause", rsc)
+	}
+
+	dest := m.destPath(suffix)
+	tmp := dest + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return errors.Wrapf(err, "Mirror: failed to create %s with cause", tmp)
+	}
+
+	n, err := io.Copy(out, rdr)
+	if cErr := out.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "Mirror: failed to download %s with cause", target)
+	}
+
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "Mirror: failed to move %s into place with cause", dest)
+	}
+
+	m.logger.Printf("Mirror: downloaded %d bytes from %s to %s", n, target, dest)
+	return nil
+}
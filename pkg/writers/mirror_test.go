@@ -0,0 +1,77 @@
+package writers
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/readers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirror(t *testing.T) {
+	logger := log.Default()
+
+	// a source index with a full chunk and two incrementals
+	source := t.TempDir() + string(filepath.Separator)
+	for _, suffix := range []string{".gz", ".1.gz", ".2.gz"} {
+		copyFile(t, "../readers/testdata/nexus-maven-repository-index.gz", source+"nexus-maven-repository-index"+suffix)
+	}
+	ts := time.UnixMilli(1243600000000).UTC()
+	props := NewIndexProperties("apache-snapshots-local", "1243533418968", ts, []int{2, 1})
+	require.NoError(t, NewProperties(logger, props, source+"nexus-maven-repository-index.properties").Write())
+
+	dest := t.TempDir()
+	files, err := NewMirror(logger, testConfig(source), dest).Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dest, "nexus-maven-repository-index.gz"),
+		filepath.Join(dest, "nexus-maven-repository-index.1.gz"),
+		filepath.Join(dest, "nexus-maven-repository-index.2.gz"),
+		filepath.Join(dest, "nexus-maven-repository-index.properties"),
+	}, files)
+
+	// files are copied verbatim
+	for _, suffix := range []string{".gz", ".2.gz", ".properties"} {
+		expected, err := os.ReadFile(source + "nexus-maven-repository-index" + suffix)
+		require.NoError(t, err)
+		actual, err := os.ReadFile(filepath.Join(dest, "nexus-maven-repository-index"+suffix))
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	}
+
+	// the mirror is readable as a local source
+	cfg := testConfig(dest + string(filepath.Separator))
+	cfg.Mode = config.Mode{Type: config.AfterChunk, After: "0"}
+	chunkNames := make(chan string, 4)
+	require.NoError(t, readers.NewIndex(logger, chunkNames, cfg).Read())
+	var targets []string
+	for chunkName := range chunkNames {
+		targets = append(targets, chunkName)
+	}
+	require.Equal(t, []string{cfg.ResolveTarget(".1.gz"), cfg.ResolveTarget(".2.gz")}, targets)
+	require.Len(t, readAll(t, cfg, targets[1]), 5)
+
+	// a later sync fetches only what may have changed
+	files, err = NewMirror(logger, testConfig(source), dest).Sync(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dest, "nexus-maven-repository-index.gz"),
+		filepath.Join(dest, "nexus-maven-repository-index.properties"),
+	}, files)
+
+	// unless the chain was rebuilt
+	props.Set(data.PropertyChainID, "1243600000000")
+	require.NoError(t, NewProperties(logger, props, source+"nexus-maven-repository-index.properties").Write())
+	rebuilt := testConfig(source)
+	rebuilt.Meta.ChainID = "1243600000000"
+	files, err = NewMirror(logger, rebuilt, dest).Sync(context.Background())
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+}
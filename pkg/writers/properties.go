@@ -0,0 +1,134 @@
+package writers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+
+	"github.com/pkg/errors"
+)
+
+type Properties struct {
+	target string
+	logger *log.Logger
+	props  data.Properties
+}
+
+// NewProperties - caller supplies the local file path of the
+// properties file to write, and the data.Properties to publish
+func NewProperties(l *log.Logger, p data.Properties, t string) Properties {
+	return Properties{
+		target: t,
+		logger: l,
+		props:  p,
+	}
+}
+
+// NewIndexProperties - compose the data.Properties describing a published index.
+// The incrementals are the published chunk IDs, most recent first, which
+// become the "nexus.index.incremental-N" entries. When incrementals is
+// non-empty, "nexus.index.last-incremental" is set to the most recent one
+func NewIndexProperties(id, chainID string, ts time.Time, incrementals []int) data.Properties {
+	props := data.NewProperties(nil)
+	props.Set(data.PropertyID, id)
+	props.Set(data.PropertyChainID, chainID)
+	props.SetTimestamp(data.PropertyTimestamp, ts)
+
+	for n, chunkID := range incrementals {
+		props.SetInt(data.IncrementalKey(n), chunkID)
+	}
+	if len(incrementals) > 0 {
+		props.SetInt(data.PropertyLastIncremental, incrementals[0])
+	}
+
+	return props
+}
+
+// Write - create the target file and populate it with the data.Properties
+func (pw Properties) Write() error {
+	path := filepath.Dir(pw.target)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return errors.Wrapf(err, "Properties: failed to create output directory at %s with cause", path)
+	}
+
+	f, err := os.Create(pw.target)
+	if err != nil {
+		return errors.Wrapf(err, "Properties: failed to create output file at %s with cause", pw.target)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := pw.Encode(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Wrapf(err, "Properties: failed to flush output file %s with cause", pw.target)
+	}
+
+	pw.logger.Printf("Properties: successfully wrote %d properties to %s", len(pw.props.Keys()), pw.target)
+	return f.Close()
+}
+
+// Encode - write the data.Properties to the supplied io.Writer in the format
+// of java.util.Properties#store, which ParseProperties (and Java) can consume
+// See: https://docs.oracle.com/javase/8/docs/api/java/util/Properties.html#store-java.io.OutputStream-java.lang.String-
+func (pw Properties) Encode(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "#%s\n", time.Now().UTC().Format("Mon Jan 02 15:04:05 MST 2006")); err != nil {
+		return errors.Wrapf(err, "Properties(%s): failed to write header with cause", pw.target)
+	}
+
+	for _, key := range pw.props.Keys() {
+		value, _ := pw.props.GetAsString(key)
+
+		line := escapeProperty(key, true) + "=" + escapeProperty(value, false) + "\n"
+		if _, err := io.WriteString(w, line); err != nil {
+			return errors.Wrapf(err, "Properties(%s): failed to write property %s with cause", pw.target, key)
+		}
+	}
+
+	return nil
+}
+
+// mirrors java.util.Properties#saveConvert: output is pure ASCII,
+// with all other chars written as \uXXXX escapes
+func escapeProperty(raw string, isKey bool) string {
+	var out strings.Builder
+	for ndx, ch := range utf16.Encode([]rune(raw)) {
+		switch {
+		case ch == ' ':
+			// all spaces in keys, but only leading spaces in values, are escaped
+			if isKey || ndx == 0 {
+				out.WriteString(`\ `)
+			} else {
+				out.WriteByte(' ')
+			}
+		case ch == '\\':
+			out.WriteString(`\\`)
+		case ch == '\t':
+			out.WriteString(`\t`)
+		case ch == '\n':
+			out.WriteString(`\n`)
+		case ch == '\r':
+			out.WriteString(`\r`)
+		case ch == '\f':
+			out.WriteString(`\f`)
+		case ch == '=' || ch == ':' || ch == '#' || ch == '!':
+			out.WriteByte('\\')
+			out.WriteByte(byte(ch))
+		case ch < 0x0020 || ch > 0x007e:
+			fmt.Fprintf(&out, `\u%04X`, ch)
+		default:
+			out.WriteByte(byte(ch))
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,102 @@
+package writers
+
+import (
+	"bytes"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/readers"
+	"github.com/elireisman/maven-index-reader-go/pkg/resources"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPropertiesRoundTrip(t *testing.T) {
+	logger := log.Default()
+	ts := time.UnixMilli(1243533418015).UTC()
+
+	props := NewIndexProperties("apache-snapshots-local", "1243533418968", ts, []int{3, 2, 1})
+	props.Set("custom key", " café = \U0001f600 # !")
+
+	target := filepath.Join(t.TempDir(), "nexus-maven-repository-index.properties")
+	require.NoError(t, NewProperties(logger, props, target).Write())
+
+	rsc, err := resources.NewLocalResource(logger, target)
+	require.NoError(t, err)
+	rdr, err := readers.NewProperties(logger, rsc)
+	require.NoError(t, err)
+	got, err := rdr.Read()
+	require.NoError(t, err)
+
+	require.Equal(t, props.Keys(), got.Keys())
+	for _, key := range props.Keys() {
+		expected, _ := props.GetAsString(key)
+		actual, err := got.GetAsString(key)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual, key)
+	}
+
+	lastIncr, err := got.GetAsInt(data.PropertyLastIncremental)
+	require.NoError(t, err)
+	require.Equal(t, 3, lastIncr)
+
+	gotTS, err := got.GetAsTimestamp(data.PropertyTimestamp)
+	require.NoError(t, err)
+	require.True(t, ts.Equal(gotTS))
+}
+
+func TestPropertiesEscaping(t *testing.T) {
+	props := data.NewProperties(map[string]string{
+		"a key": " leading space, é",
+	})
+
+	var buffer bytes.Buffer
+	require.NoError(t, NewProperties(log.Default(), props, "").Encode(&buffer))
+	require.Contains(t, buffer.String(), "\na\\ key=\\ leading space, \\u00E9\n")
+}
+
+func TestIndexWithoutIncrementalsRoundTrip(t *testing.T) {
+	logger := log.Default()
+
+	// an index published by this package before its first incremental
+	base := t.TempDir() + string(filepath.Separator)
+	cfg := testConfig(base)
+	copyFile(t, "../readers/testdata/nexus-maven-repository-index.gz", cfg.ResolveTarget(".gz"))
+	props := NewIndexProperties(cfg.Meta.ID, cfg.Meta.ChainID, time.UnixMilli(1243533418015).UTC(), nil)
+	require.NoError(t, NewProperties(logger, props, cfg.ResolveTarget(".properties")).Write())
+
+	readIndex := func(mode config.Mode) ([]string, error) {
+		cfg.Mode = mode
+		require.NoError(t, config.Validate(logger, cfg))
+
+		chunkNames := make(chan string, 4)
+		err := readers.NewIndex(logger, chunkNames, cfg).Read()
+		var out []string
+		for chunkName := range chunkNames {
+			out = append(out, chunkName)
+		}
+		return out, err
+	}
+
+	// the full index is read without a last incremental
+	chunkNames, err := readIndex(config.Mode{Type: config.All})
+	require.NoError(t, err)
+	require.Equal(t, []string{cfg.ResolveTarget(".gz")}, chunkNames)
+
+	// incremental reads still require one
+	_, err = readIndex(config.Mode{Type: config.AfterChunk, After: "0"})
+	require.ErrorContains(t, err, data.PropertyLastIncremental)
+
+	// until the first incremental is published
+	current := make(chan data.Record)
+	close(current)
+	_, err = NewPublisher(logger, cfg).Publish(current, time.UnixMilli(1243600000000).UTC())
+	require.NoError(t, err)
+	chunkNames, err = readIndex(config.Mode{Type: config.AfterChunk, After: "0"})
+	require.NoError(t, err)
+	require.Equal(t, []string{cfg.ResolveTarget(".1.gz")}, chunkNames)
+}
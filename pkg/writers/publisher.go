@@ -0,0 +1,384 @@
+package writers
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/data/types/record/keys"
+	"github.com/elireisman/maven-index-reader-go/pkg/readers"
+	"github.com/elireisman/maven-index-reader-go/pkg/resources"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxIncrementals - the number of "nexus.index.incremental-N"
+// entries retained in the properties file, as in the Java indexer
+const DefaultMaxIncrementals = 30
+
+// Publisher - publishes incremental updates to a local index directory
+// (config.Index.Source.Base) laid out as readers.Index expects. The
+// directory must already contain a full chunk and properties file, as
+// produced by writers.Chunk and writers.Properties
+type Publisher struct {
+	cfg             config.Index
+	logger          *log.Logger
+	maxIncrementals int
+}
+
+func NewPublisher(l *log.Logger, c config.Index) Publisher {
+	return Publisher{
+		cfg:             c,
+		logger:          l,
+		maxIncrementals: DefaultMaxIncrementals,
+	}
+}
+
+// Publish - diff the current set of ARTIFACT_ADD data.Records consumed from
+// the input channel against the previous state of the index, then write the
+// differences as the next ".N.gz" incremental chunk. The full chunk is
+// rewritten to reflect the current set, so it can serve as the previous state
+// for the next run, but only moved into place once the properties file
+// announcing the chunk is written. Returns the ID of the published chunk.
+func (p Publisher) Publish(current <-chan data.Record, ts time.Time) (int, error) {
+	props, err := p.readProperties()
+	if err != nil {
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+
+	// an index without incrementals, as from NewIndexProperties, omits the last
+	lastIncr, _, err := props.LastIncremental()
+	if err != nil {
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+	chunkID := lastIncr + 1
+
+	if err := p.recoverStaged(props); err != nil {
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+	previous, err := p.readPrevious()
+	if err != nil {
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+
+	// compute ARTIFACT_ADD records for new or modified artifacts
+	var diff []data.Record
+	var artifacts []data.Record
+	seen := map[string]bool{}
+	for record := range current {
+		if record.Type() != data.ArtifactAdd {
+			continue
+		}
+
+		uinfo, raw, err := identify(record)
+		if err != nil {
+			return 0, errors.Wrap(err, "from Publisher#Publish")
+		}
+		if seen[uinfo] {
+			continue
+		}
+		seen[uinfo] = true
+		artifacts = append(artifacts, record)
+
+		if prevRaw, found := previous[uinfo]; !found || !sameArtifact(prevRaw, raw) {
+			diff = append(diff, record)
+		}
+	}
+
+	// compute ARTIFACT_REMOVE records for artifacts no longer present
+	var removed []string
+	for uinfo := range previous {
+		if !seen[uinfo] {
+			removed = append(removed, uinfo)
+		}
+	}
+	sort.Strings(removed)
+	for _, uinfo := range removed {
+		record, err := data.NewRecord(p.logger, map[string]string{
+			keys.Del:               uinfo,
+			data.RecordModifiedKey: strconv.FormatInt(ts.UnixMilli(), 10),
+		})
+		if err != nil {
+			return 0, errors.Wrapf(err, "Publisher: failed to compose removal of %s with cause", uinfo)
+		}
+		diff = append(diff, record)
+	}
+	p.logger.Printf("Publisher: resolved %d added or modified, and %d removed artifacts", len(diff)-len(removed), len(removed))
+
+	descriptor, err := p.descriptor()
+	if err != nil {
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+
+	// the incremental chunk must exist before the properties file announces it
+	target := p.cfg.ResolveTarget(".%d.gz", chunkID)
+	if err := p.writeChunk(target, ts, append([]data.Record{descriptor}, diff...)); err != nil {
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+
+	groups, err := groupRecords(p.logger, artifacts)
+	if err != nil {
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+
+	// the properties file is the commit point: until it announces the chunk,
+	// the full chunk must remain the previous state a rerun diffs against
+	fullTarget := p.cfg.ResolveTarget(".gz")
+	full := append([]data.Record{descriptor}, artifacts...)
+	fullTmp, err := p.stageChunk(fullTarget, ts, append(full, groups...))
+	if err != nil {
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+
+	if err := p.writeProperties(props, chunkID, ts); err != nil {
+		os.Remove(fullTmp)
+		return 0, errors.Wrap(err, "from Publisher#Publish")
+	}
+	if err := os.Rename(fullTmp, fullTarget); err != nil {
+		return 0, errors.Wrapf(err, "Publisher: failed to move chunk into place at %s with cause", fullTarget)
+	}
+
+	p.logger.Printf("Publisher: successfully published incremental chunk %d to %s", chunkID, target)
+	return chunkID, nil
+}
+
+func (p Publisher) readProperties() (data.Properties, error) {
+	target := p.cfg.ResolveTarget(".properties")
+	rsc, err := resources.FromConfig(p.logger, p.cfg, target)
+	if err != nil {
+		return data.Properties{}, errors.Wrapf(err, "Publisher: failed to resolve previous properties at %s with cause", target)
+	}
+
+	rdr, err := readers.NewProperties(p.logger, rsc)
+	if err != nil {
+		return data.Properties{}, err
+	}
+
+	props, err := rdr.Read()
+	if err != nil {
+		return data.Properties{}, err
+	}
+
+	indexID, err := props.GetAsString(data.PropertyID)
+	if err != nil {
+		return data.Properties{}, err
+	}
+	if p.cfg.Meta.ID != indexID {
+		return data.Properties{}, errors.Errorf("Publisher: expected index ID %s, got: %s", p.cfg.Meta.ID, indexID)
+	}
+
+	chainID, err := props.GetAsString(data.PropertyChainID)
+	if err != nil {
+		return data.Properties{}, err
+	}
+	if p.cfg.Meta.ChainID != chainID {
+		return data.Properties{}, errors.Errorf("Publisher: expected chain ID %s, got: %s", p.cfg.Meta.ChainID, chainID)
+	}
+
+	return props, nil
+}
+
+// recoverStaged - a full chunk staged by a run that stopped once the properties
+// file was written, but before moving the chunk into place, is the previous
+// state, as its timestamp matches the properties. Any other is discarded
+func (p Publisher) recoverStaged(props data.Properties) error {
+	target := p.cfg.ResolveTarget(".gz")
+	tmp := target + ".tmp"
+	if _, err := os.Stat(tmp); os.IsNotExist(err) {
+		return nil
+	}
+
+	published, err := props.GetAsTimestamp(data.PropertyTimestamp)
+	if err != nil {
+		return err
+	}
+
+	it := readers.OpenChunk(p.logger, p.cfg, tmp, func(data.Record) bool { return false })
+	for it.Next() {
+	}
+	staged := it.Timestamp()
+	it.Close()
+
+	if it.Err() == nil && staged.UnixMilli() == published.UnixMilli() {
+		p.logger.Printf("Publisher: recovering full chunk staged at %s", staged)
+		if err := os.Rename(tmp, target); err != nil {
+			return errors.Wrapf(err, "Publisher: failed to move chunk into place at %s with cause", target)
+		}
+		return nil
+	}
+
+	p.logger.Printf("Publisher: discarding unpublished full chunk %s", tmp)
+	if err := os.Remove(tmp); err != nil {
+		return errors.Wrapf(err, "Publisher: failed to remove unpublished chunk %s with cause", tmp)
+	}
+	return nil
+}
+
+// map the raw form of each ARTIFACT_ADD record in the full chunk by its unique "uinfo" key
+func (p Publisher) readPrevious() (map[string]map[string]string, error) {
+	target := p.cfg.ResolveTarget(".gz")
+	it := readers.OpenChunk(p.logger, p.cfg, target, func(r data.Record) bool {
+		return r.Type() == data.ArtifactAdd
+	})
+	defer it.Close()
+
+	out := map[string]map[string]string{}
+	for it.Next() {
+		uinfo, raw, err := identify(it.Record())
+		if err != nil {
+			return nil, err
+		}
+		out[uinfo] = raw
+	}
+	if err := it.Err(); err != nil {
+		return nil, errors.Wrapf(err, "Publisher: failed to read previous full chunk %s with cause", target)
+	}
+
+	return out, nil
+}
+
+func (p Publisher) descriptor() (data.Record, error) {
+	return data.NewRecord(p.logger, map[string]string{
+		keys.Descriptor: data.DescriptorValue,
+		data.IDXINFO:    data.DescriptorVersion + data.RecordValueSeparator + p.cfg.Meta.ID,
+	})
+}
+
+// write aside, then swap into place, so readers never observe a partial chunk
+func (p Publisher) writeChunk(target string, ts time.Time, records []data.Record) error {
+	tmp, err := p.stageChunk(target, ts, records)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return errors.Wrapf(err, "Publisher: failed to move chunk into place at %s with cause", target)
+	}
+
+	return nil
+}
+
+// stageChunk - write the chunk beside target, returning
+// the path to move into place once it should be published
+func (p Publisher) stageChunk(target string, ts time.Time, records []data.Record) (string, error) {
+	queue := make(chan data.Record, len(records))
+	for _, record := range records {
+		queue <- record
+	}
+	close(queue)
+
+	tmp := target + ".tmp"
+	if err := NewChunk(p.logger, queue, tmp, ts).Write(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	return tmp, nil
+}
+
+// record the new chunk as "incremental-0", shifting older chunk IDs down the list
+func (p Publisher) writeProperties(props data.Properties, chunkID int, ts time.Time) error {
+	incrementals := []int{chunkID}
+	for n := 0; ; n++ {
+		prevID, err := props.GetAsInt(data.IncrementalKey(n))
+		if err != nil {
+			break
+		}
+		props.Delete(data.IncrementalKey(n))
+		incrementals = append(incrementals, prevID)
+	}
+	if len(incrementals) > p.maxIncrementals {
+		incrementals = incrementals[:p.maxIncrementals]
+	}
+
+	for n, id := range incrementals {
+		props.SetInt(data.IncrementalKey(n), id)
+	}
+	props.SetInt(data.PropertyLastIncremental, chunkID)
+	props.SetTimestamp(data.PropertyTimestamp, ts)
+
+	target := p.cfg.ResolveTarget(".properties")
+	tmp := target + ".tmp"
+	if err := NewProperties(p.logger, props, tmp).Write(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return errors.Wrapf(err, "Publisher: failed to move properties into place at %s with cause", target)
+	}
+
+	return nil
+}
+
+// resolve an ARTIFACT_ADD record's unique "uinfo" key and raw form
+func identify(record data.Record) (string, map[string]string, error) {
+	raw, err := data.CompactRecord(record)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Publisher: failed to compact record with cause")
+	}
+
+	return raw[data.UInfoKey], raw, nil
+}
+
+// artifacts are unchanged if all raw fields but the record timestamp match
+func sameArtifact(prev, curr map[string]string) bool {
+	for key, value := range curr {
+		if key != data.RecordModifiedKey && prev[key] != value {
+			return false
+		}
+	}
+	for key := range prev {
+		if _, found := curr[key]; !found && key != data.RecordModifiedKey {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compose the "all groups" and "root groups" records from the groupIds of the artifacts
+func groupRecords(l *log.Logger, artifacts []data.Record) ([]data.Record, error) {
+	all := map[string]bool{}
+	roots := map[string]bool{}
+	for _, record := range artifacts {
+		groupID, ok := record.Get(keys.GroupID).(string)
+		if !ok || len(groupID) == 0 {
+			continue
+		}
+		all[groupID] = true
+		root, _, _ := strings.Cut(groupID, ".")
+		roots[root] = true
+	}
+
+	rootGroups, err := data.NewRecord(l, map[string]string{
+		keys.RootGroups:     keys.RootGroups,
+		keys.RootGroupsList: strings.Join(sortedKeys(roots), data.RecordValueSeparator),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Publisher: failed to compose root groups record with cause")
+	}
+
+	allGroups, err := data.NewRecord(l, map[string]string{
+		keys.AllGroups:     keys.AllGroups,
+		keys.AllGroupsList: strings.Join(sortedKeys(all), data.RecordValueSeparator),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Publisher: failed to compose all groups record with cause")
+	}
+
+	return []data.Record{rootGroups, allGroups}, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for key := range set {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+
+	return out
+}
@@ -0,0 +1,161 @@
+package writers
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elireisman/maven-index-reader-go/pkg/config"
+	"github.com/elireisman/maven-index-reader-go/pkg/data"
+	"github.com/elireisman/maven-index-reader-go/pkg/readers"
+
+	"github.com/stretchr/testify/require"
+)
+
+func copyFile(t *testing.T, src, dst string) {
+	in, err := os.Open(src)
+	require.NoError(t, err)
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	require.NoError(t, err)
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	require.NoError(t, err)
+}
+
+func TestPublisher(t *testing.T) {
+	logger := log.Default()
+
+	base := t.TempDir() + string(filepath.Separator)
+	for _, ext := range []string{".gz", ".properties"} {
+		copyFile(t, "../readers/testdata/nexus-maven-repository-index"+ext, base+"nexus-maven-repository-index"+ext)
+	}
+	cfg := testConfig(base)
+
+	previous := readAll(t, cfg, cfg.ResolveTarget(".gz"))
+	require.Equal(t, data.ArtifactAdd, previous[0].Type())
+	require.Equal(t, data.ArtifactAdd, previous[1].Type())
+
+	added, err := data.NewRecord(logger, map[string]string{
+		data.UInfoKey:          "org.example|widget|1.0|NA|jar",
+		data.InfoKey:           "jar|1243533415343|1024|1|0|1|jar",
+		data.RecordModifiedKey: "1243533417953",
+		data.SHA1Key:           "38bb5a445e9aa5a38581743ede58f46c0f1ce321",
+	})
+	require.NoError(t, err)
+
+	// keep the 1st artifact, drop the 2nd, and add a new one
+	current := make(chan data.Record, 3)
+	current <- previous[0]
+	current <- added
+	close(current)
+
+	ts := time.UnixMilli(1243600000000).UTC()
+	chunkID, err := NewPublisher(logger, cfg).Publish(current, ts)
+	require.NoError(t, err)
+	require.Equal(t, 1, chunkID)
+
+	// the existing reader consumes the output in incremental mode
+	cfg.Mode = config.Mode{Type: config.AfterChunk, After: "0"}
+	require.NoError(t, config.Validate(logger, cfg))
+
+	chunkNames := make(chan string, 4)
+	require.NoError(t, readers.NewIndex(logger, chunkNames, cfg).Read())
+	var targets []string
+	for chunkName := range chunkNames {
+		targets = append(targets, chunkName)
+	}
+	require.Equal(t, []string{cfg.ResolveTarget(".1.gz")}, targets)
+
+	incremental := readAll(t, cfg, targets[0])
+	require.Len(t, incremental, 3)
+	require.Equal(t, data.Descriptor, incremental[0].Type())
+	require.Equal(t, "apache-snapshots-local", incremental[0].Get("repositoryId"))
+	require.Equal(t, data.ArtifactAdd, incremental[1].Type())
+	require.Equal(t, "widget", incremental[1].Get("artifactId"))
+	require.Equal(t, data.ArtifactRemove, incremental[2].Type())
+	require.Equal(t, previous[1].Get("artifactId"), incremental[2].Get("artifactId"))
+	require.Equal(t, ts, incremental[2].Get("recordModified"))
+
+	// the full chunk now reflects the current artifact set
+	full := readAll(t, cfg, cfg.ResolveTarget(".gz"))
+	require.Len(t, full, 5)
+	require.Equal(t, previous[0].Get("artifactId"), full[1].Get("artifactId"))
+	require.Equal(t, "widget", full[2].Get("artifactId"))
+	require.Equal(t, []string{"org"}, full[3].Get("rootGroupsList"))
+	require.Equal(t, []string{"org.example", "org.sonatype.nexus"}, full[4].Get("allGroupsList"))
+
+	// a 2nd publish with no changes yields an empty incremental chunk
+	current = make(chan data.Record, 2)
+	current <- previous[0]
+	current <- added
+	close(current)
+
+	chunkID, err = NewPublisher(logger, cfg).Publish(current, ts.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, chunkID)
+	require.Len(t, readAll(t, cfg, cfg.ResolveTarget(".2.gz")), 1)
+
+	raw, err := os.ReadFile(cfg.ResolveTarget(".properties"))
+	require.NoError(t, err)
+	props, err := readers.ParseProperties(string(raw))
+	require.NoError(t, err)
+	require.Equal(t, "2", props[data.PropertyLastIncremental])
+	require.Equal(t, "2", props[data.IncrementalKey(0)])
+	require.Equal(t, "1", props[data.IncrementalKey(1)])
+}
+
+func TestPublisherCommitPoint(t *testing.T) {
+	logger := log.Default()
+
+	// an index composed by this package, with no incrementals yet
+	base := t.TempDir() + string(filepath.Separator)
+	copyFile(t, "../readers/testdata/nexus-maven-repository-index.gz", base+"nexus-maven-repository-index.gz")
+	cfg := testConfig(base)
+	props := NewIndexProperties(cfg.Meta.ID, cfg.Meta.ChainID, time.UnixMilli(1243533418015).UTC(), nil)
+	require.NoError(t, NewProperties(logger, props, cfg.ResolveTarget(".properties")).Write())
+
+	previous := readAll(t, cfg, cfg.ResolveTarget(".gz"))
+	publish := func(ts time.Time) (int, error) {
+		current := make(chan data.Record, 1)
+		current <- previous[0]
+		close(current)
+		return NewPublisher(logger, cfg).Publish(current, ts)
+	}
+	original, err := os.ReadFile(cfg.ResolveTarget(".gz"))
+	require.NoError(t, err)
+
+	// the properties file can't be written, so nothing is
+	// announced, and the full chunk remains the previous state
+	propsTmp := cfg.ResolveTarget(".properties") + ".tmp"
+	require.NoError(t, os.Mkdir(propsTmp, 0o755))
+	_, err = publish(time.UnixMilli(1243600000000).UTC())
+	require.Error(t, err)
+	unchanged, err := os.ReadFile(cfg.ResolveTarget(".gz"))
+	require.NoError(t, err)
+	require.Equal(t, original, unchanged)
+	require.NoFileExists(t, cfg.ResolveTarget(".gz")+".tmp")
+	require.NoError(t, os.RemoveAll(propsTmp))
+
+	// the first incremental follows the properties' missing last incremental
+	chunkID, err := publish(time.UnixMilli(1243600000000).UTC())
+	require.NoError(t, err)
+	require.Equal(t, 1, chunkID)
+	require.Len(t, readAll(t, cfg, cfg.ResolveTarget(".1.gz")), 2)
+
+	// a run stopping once the properties are written, before the full chunk
+	// is moved into place, leaves it staged; the next run recovers it, rather
+	// than diffing against the full chunk from before, and repeating chunk 1
+	require.NoError(t, os.Rename(cfg.ResolveTarget(".gz"), cfg.ResolveTarget(".gz")+".tmp"))
+	require.NoError(t, os.WriteFile(cfg.ResolveTarget(".gz"), original, 0o644))
+	chunkID, err = publish(time.UnixMilli(1243700000000).UTC())
+	require.NoError(t, err)
+	require.Equal(t, 2, chunkID)
+	require.Len(t, readAll(t, cfg, cfg.ResolveTarget(".2.gz")), 1)
+	require.NoFileExists(t, cfg.ResolveTarget(".gz")+".tmp")
+}